@@ -0,0 +1,291 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+	"github.com/YujiAzama/orionctl/orion"
+)
+
+var resetStats bool
+
+var metricsCmd = &cobra.Command{
+	Use:   "metrics",
+	Short: "Get Orion metrics",
+	Long: `Get the metrics of Orion by service and service path
+
+Orion must run with metrics enabled, which is the default. The metrics of
+the service and service path given with -s and -P are shown when they are
+set, and those of every tenant and their total otherwise. With --reset,
+Orion resets the metrics after reporting them.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		metrics, err := client.GetMetrics(cmd.Context(), resetStats)
+		if err != nil {
+			return err
+		}
+		var services []string
+		for service := range metrics.Services {
+			services = append(services, service)
+		}
+		sort.Strings(services)
+		return printResources(metrics, false, services, func(wide bool) *uitable.Table {
+			return metricsTable(metrics, wide)
+		})
+	},
+}
+
+var statisticsCmd = &cobra.Command{
+	Use:   "statistics",
+	Short: "Get Orion statistics",
+	Long: `Get the statistics of Orion
+
+The statistics reported depend on the -statCounters, -statSemWait,
+-statTiming and -statNotifQueue options Orion runs with. With --reset, the
+statistics are reset after they are shown.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		statistics, err := client.GetStatistics(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if err := printResources(statistics, false, sortedKeys(statistics), func(wide bool) *uitable.Table {
+			return statisticsTable(statistics)
+		}); err != nil {
+			return err
+		}
+		if resetStats {
+			return client.ResetStatistics(cmd.Context())
+		}
+		return nil
+	},
+}
+
+var cacheStatisticsCmd = &cobra.Command{
+	Use:   "cache-statistics",
+	Short: "Get statistics of the Orion subscription cache",
+	Long: `Get the statistics of the subscription cache of Orion
+
+With --reset, the statistics are reset after they are shown.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		statistics, err := client.GetCacheStatistics(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if err := printResources(statistics, false, sortedKeys(statistics), func(wide bool) *uitable.Table {
+			return statisticsTable(statistics)
+		}); err != nil {
+			return err
+		}
+		if resetStats {
+			return client.ResetCacheStatistics(cmd.Context())
+		}
+		return nil
+	},
+}
+
+var logLevelCmd = &cobra.Command{
+	Use:   "log-level [LEVEL]",
+	Short: "Get or set the Orion log level",
+	Long: `Get the log level of Orion, or set it to LEVEL
+
+LEVEL is one of: ` + strings.Join(orion.LogLevels, ", "),
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 1 {
+			return newUsageError("accepts at most one log level")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var level string
+		if len(args) == 1 {
+			level = strings.ToUpper(args[0])
+			if !containsString(orion.LogLevels, level) {
+				return newUsageError("unknown log level \"%s\". One of: %s", args[0], strings.Join(orion.LogLevels, ", "))
+			}
+		}
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		if level != "" {
+			if err := client.SetLogLevel(cmd.Context(), level); err != nil {
+				return err
+			}
+			fmt.Printf("log level set to %s\n", level)
+			return nil
+		}
+		level, err = client.GetLogLevel(cmd.Context())
+		if err != nil {
+			return err
+		}
+		return printResources(map[string]string{"level": level}, false, []string{level}, func(wide bool) *uitable.Table {
+			table := uitable.New()
+			table.AddRow("Level", level)
+			return table
+		})
+	},
+}
+
+var semaphoresCmd = &cobra.Command{
+	Use:     "semaphores",
+	Aliases: []string{"sem"},
+	Short:   "Get the state of the Orion semaphores",
+	Long:    "Get the state of the internal semaphores of Orion",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		semaphores, err := client.GetSemaphores(cmd.Context())
+		if err != nil {
+			return err
+		}
+		return printResources(semaphores, false, sortedKeys(semaphores), func(wide bool) *uitable.Table {
+			return semaphoresTable(semaphores)
+		})
+	},
+}
+
+// metricNames are the metrics shown by metricsTable, the wide ones last.
+var metricNames = []string{
+	"incomingTransactions",
+	"incomingTransactionErrors",
+	"serviceTime",
+	"outgoingTransactions",
+	"outgoingTransactionErrors",
+	"incomingTransactionRequestSize",
+	"incomingTransactionResponseSize",
+	"outgoingTransactionRequestSize",
+	"outgoingTransactionResponseSize",
+}
+
+const wideMetrics = 5
+
+func metricsTable(metrics *orion.MetricsReport, wide bool) *uitable.Table {
+	names := metricNames
+	header := []interface{}{"Service", "Service Path", "Incoming", "Errors", "Service Time", "Outgoing", "Outgoing Errors"}
+	if wide {
+		header = append(header, "Incoming Request Size", "Incoming Response Size", "Outgoing Request Size", "Outgoing Response Size")
+	} else {
+		names = names[:wideMetrics]
+	}
+	row := func(service, servicePath string, m orion.Metrics) []interface{} {
+		cells := []interface{}{service, servicePath}
+		for _, name := range names {
+			value, ok := m[name]
+			if !ok {
+				cells = append(cells, "")
+				continue
+			}
+			cells = append(cells, strconv.FormatFloat(value, 'f', -1, 64))
+		}
+		return cells
+	}
+
+	table := uitable.New()
+	table.AddRow(header...)
+	var services []string
+	for service := range metrics.Services {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	for _, service := range services {
+		if fs != "" && service != fs {
+			continue
+		}
+		var paths []string
+		for path := range metrics.Services[service].Subservs {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			if fsp != "" && path != fsp {
+				continue
+			}
+			table.AddRow(row(service, path, metrics.Services[service].Subservs[path])...)
+		}
+	}
+	if fs == "" && fsp == "" && len(metrics.Sum.Sum) > 0 {
+		table.AddRow(row("(all)", "", metrics.Sum.Sum)...)
+	}
+	return table
+}
+
+func statisticsTable(statistics map[string]interface{}) *uitable.Table {
+	fields := map[string]string{}
+	flattenFields("", statistics, fields)
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	table := uitable.New()
+	table.MaxColWidth = 80
+	table.Wrap = true
+	for _, name := range names {
+		table.AddRow(name, fields[name])
+	}
+	return table
+}
+
+func semaphoresTable(semaphores map[string]interface{}) *uitable.Table {
+	table := uitable.New()
+	table.AddRow("Semaphore", "Status", "Details")
+	for _, name := range sortedKeys(semaphores) {
+		state, _ := semaphores[name].(map[string]interface{})
+		var details []string
+		for _, key := range sortedKeys(state) {
+			if key != "status" {
+				details = append(details, key+"="+formatValue(state[key]))
+			}
+		}
+		status := ""
+		if s, ok := state["status"]; ok {
+			status = formatValue(s)
+		}
+		table.AddRow(name, status, strings.Join(details, " "))
+	}
+	return table
+}
+
+func init() {
+	rootCmd.AddCommand(metricsCmd)
+	rootCmd.AddCommand(statisticsCmd)
+	rootCmd.AddCommand(cacheStatisticsCmd)
+	rootCmd.AddCommand(logLevelCmd)
+	rootCmd.AddCommand(semaphoresCmd)
+	metricsCmd.Flags().BoolVar(&resetStats, "reset", false, "Reset the metrics after reporting them")
+	statisticsCmd.Flags().BoolVar(&resetStats, "reset", false, "Reset the statistics after showing them")
+	cacheStatisticsCmd.Flags().BoolVar(&resetStats, "reset", false, "Reset the statistics after showing them")
+}
@@ -0,0 +1,312 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/YujiAzama/orionctl/orion"
+)
+
+var prune bool
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply a configuration to Orion resources",
+	Long: `Apply a configuration to Orion resources by filename
+
+Every document of the files given with -f is matched to an existing resource
+in the current service and service path, which is updated when it differs
+from the document, and created when there is no match:
+
+  Subscription  matched by description, which must be unique. A patch
+                cannot remove fields, so a subscription whose document
+                leaves out a field it has is replaced by a new one
+  Registration  matched by description or, without one, by provider URL,
+                entities and attributes. Orion cannot patch registrations,
+                so a changed registration is replaced by a new one
+  Entity        matched by id and type
+
+With --prune, subscriptions and registrations of the kinds present in the
+files that match no document are deleted.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manifests, err := readManifests(filename, recursive)
+		if err != nil {
+			return err
+		}
+		for _, m := range manifests {
+			if m.Kind == "" {
+				return newUsageError("%s: kind is required, must be one of %s", m.Source, strings.Join(manifestKinds, ", "))
+			}
+		}
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		live, err := loadLiveState(cmd.Context(), client, currentTenant())
+		if err != nil {
+			return err
+		}
+		return applyManifests(cmd.Context(), client, live, manifests)
+	},
+}
+
+// liveState holds the subscriptions and registrations of a tenant, as
+// Orion returns them, indexed by subscriptionKey and registrationKey.
+type liveState struct {
+	tenant        tenant
+	subscriptions map[string][]map[string]interface{}
+	registrations map[string][]map[string]interface{}
+}
+
+func loadLiveState(ctx context.Context, client *orion.Client, t tenant) (*liveState, error) {
+	subscriptions, err := client.GetRawSubscriptions(ctx, t.Service, t.ServicePath)
+	if err != nil {
+		return nil, err
+	}
+	registrations, err := client.GetRawRegistrations(ctx, t.Service, t.ServicePath)
+	if err != nil {
+		return nil, err
+	}
+	live := &liveState{
+		tenant:        t,
+		subscriptions: map[string][]map[string]interface{}{},
+		registrations: map[string][]map[string]interface{}{},
+	}
+	for _, item := range subscriptions {
+		subscription, err := decodeResource(item)
+		if err != nil {
+			return nil, err
+		}
+		key := subscriptionKey(subscription)
+		live.subscriptions[key] = append(live.subscriptions[key], subscription)
+	}
+	for _, item := range registrations {
+		registration, err := decodeResource(item)
+		if err != nil {
+			return nil, err
+		}
+		key := registrationKey(registration)
+		live.registrations[key] = append(live.registrations[key], registration)
+	}
+	return live, nil
+}
+
+// subscription returns the live subscription matching key, or nil when
+// there is none.
+func (live *liveState) subscription(key string) (map[string]interface{}, error) {
+	switch matches := live.subscriptions[key]; len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("%d subscriptions have this description", len(matches))
+	}
+}
+
+// registration returns the live registration matching key, or nil when
+// there is none.
+func (live *liveState) registration(key string) (map[string]interface{}, error) {
+	switch matches := live.registrations[key]; len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("%d registrations match", len(matches))
+	}
+}
+
+func applyManifests(ctx context.Context, client *orion.Client, live *liveState, manifests []manifest) error {
+	sortManifests(manifests)
+	declared := map[string]map[string]bool{}
+	var failed []error
+	for _, m := range manifests {
+		key, action, err := applyManifest(ctx, client, live, m)
+		if declared[m.Kind] == nil {
+			declared[m.Kind] = map[string]bool{}
+		}
+		declared[m.Kind][key] = true
+		if err != nil {
+			err = fmt.Errorf("%s: %w", m.Source, err)
+			if len(manifests) > 1 {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+			failed = append(failed, err)
+			continue
+		}
+		fmt.Printf("%s \"%s\" %s\n", strings.ToLower(m.Kind), key, action)
+	}
+
+	if prune && len(failed) == 0 {
+		if declared["Subscription"] != nil {
+			for key, subscriptions := range live.subscriptions {
+				if declared["Subscription"][key] {
+					continue
+				}
+				for _, subscription := range subscriptions {
+					id := stringField(subscription, "id")
+					if err := client.DeleteSubscription(ctx, id, live.tenant.Service, live.tenant.ServicePath); err != nil {
+						return fmt.Errorf("subscription \"%s\": %w", id, err)
+					}
+					fmt.Printf("subscription \"%s\" pruned\n", id)
+				}
+			}
+		}
+		if declared["Registration"] != nil {
+			for key, registrations := range live.registrations {
+				if declared["Registration"][key] {
+					continue
+				}
+				for _, registration := range registrations {
+					id := stringField(registration, "id")
+					if err := client.DeleteRegistration(ctx, id, live.tenant.Service, live.tenant.ServicePath); err != nil {
+						return fmt.Errorf("registration \"%s\": %w", id, err)
+					}
+					fmt.Printf("registration \"%s\" pruned\n", id)
+				}
+			}
+		}
+	}
+
+	switch {
+	case len(failed) == 0:
+		return nil
+	case len(manifests) == 1:
+		return failed[0]
+	case prune:
+		return fmt.Errorf("%d of %d resources could not be applied, nothing was pruned", len(failed), len(manifests))
+	default:
+		return fmt.Errorf("%d of %d resources could not be applied", len(failed), len(manifests))
+	}
+}
+
+// applyManifest creates or updates the resource described by m. It returns
+// the key the resource was matched by and the action taken.
+func applyManifest(ctx context.Context, client *orion.Client, live *liveState, m manifest) (string, string, error) {
+	switch m.Kind {
+	case "Subscription":
+		var desired map[string]interface{}
+		if err := m.decode(&desired); err != nil {
+			return "", "", err
+		}
+		key := subscriptionKey(desired)
+		if key == "" {
+			return "", "", newUsageError("a description is required to apply a subscription")
+		}
+		current, err := live.subscription(key)
+		if err != nil {
+			return key, "", err
+		}
+		if current == nil {
+			_, err := client.CreateSubscription(ctx, desired, live.tenant.Service, live.tenant.ServicePath)
+			return key, "created", err
+		}
+		if sameJSON(normalizeResourcePair("Subscription", desired, current)) {
+			return key, "unchanged", nil
+		}
+		id := stringField(current, "id")
+		if len(removedFields("Subscription", desired, current)) == 0 {
+			return key, "configured", client.PatchSubscription(ctx, id, desired, live.tenant.Service, live.tenant.ServicePath)
+		}
+		// A patch cannot remove fields such as throttling or expires, so
+		// the subscription is replaced, keeping it paused when it is.
+		if _, ok := desired["status"]; !ok && current["status"] == "inactive" {
+			desired["status"] = "inactive"
+		}
+		if _, err := client.CreateSubscription(ctx, desired, live.tenant.Service, live.tenant.ServicePath); err != nil {
+			return key, "", err
+		}
+		return key, "configured", client.DeleteSubscription(ctx, id, live.tenant.Service, live.tenant.ServicePath)
+	case "Registration":
+		var desired map[string]interface{}
+		if err := m.decode(&desired); err != nil {
+			return "", "", err
+		}
+		key := registrationKey(desired)
+		current, err := live.registration(key)
+		if err != nil {
+			return key, "", err
+		}
+		if current == nil {
+			_, err := client.CreateRegistration(ctx, desired, live.tenant.Service, live.tenant.ServicePath)
+			return key, "created", err
+		}
+		if sameJSON(normalizeResourcePair("Registration", desired, current)) {
+			return key, "unchanged", nil
+		}
+		// Orion does not implement PATCH for registrations. The new
+		// registration is created first so that a rejected one leaves the
+		// current registration in place.
+		if _, err := client.CreateRegistration(ctx, desired, live.tenant.Service, live.tenant.ServicePath); err != nil {
+			return key, "", err
+		}
+		return key, "configured", client.DeleteRegistration(ctx, stringField(current, "id"), live.tenant.Service, live.tenant.ServicePath)
+	case "Entity":
+		var desired orion.Entity
+		if err := m.decode(&desired); err != nil {
+			return "", "", err
+		}
+		key := entityKey(&desired)
+		current, err := client.GetEntity(ctx, desired.Id, desired.Type, live.tenant.Service, live.tenant.ServicePath)
+		if isNotFound(err) {
+			_, err := client.CreateEntity(ctx, desired, live.tenant.Service, live.tenant.ServicePath)
+			return key, "created", err
+		}
+		if err != nil {
+			return key, "", err
+		}
+		if sameJSON(normalizeEntity(desired), normalizeEntity(*current)) {
+			return key, "unchanged", nil
+		}
+		return key, "configured", client.ReplaceEntityAttrs(ctx, desired, live.tenant.Service, live.tenant.ServicePath)
+	}
+	return "", "", newUsageError("unknown kind \"%s\"", m.Kind)
+}
+
+func isNotFound(err error) bool {
+	var orionErr *orion.Error
+	return errors.As(err, &orionErr) && orionErr.StatusCode == http.StatusNotFound
+}
+
+// sameJSON reports whether a and b have the same JSON encoding.
+func sameJSON(a, b interface{}) bool {
+	x, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	y, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(x, y)
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+	applyCmd.Flags().StringVarP(&filename, "filename", "f", "", "Resource file, directory, URL or - for stdin")
+	applyCmd.Flags().BoolVarP(&recursive, "recursive", "R", false, "Process the directory given with -f recursively")
+	applyCmd.Flags().BoolVar(&prune, "prune", false, "Delete subscriptions and registrations that are not declared in the files")
+}
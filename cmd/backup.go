@@ -0,0 +1,186 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/YujiAzama/orionctl/orion"
+)
+
+var archiveFile string
+var tenantNames []string
+
+// backupIndex describes the content of a backup archive. It is stored as
+// index.json next to one directory per tenant holding entities.json,
+// registrations.json and subscriptions.json.
+type backupIndex struct {
+	Created string         `json:"created"`
+	Tenants []backupTenant `json:"tenants"`
+}
+
+type backupTenant struct {
+	tenant
+	Entities      int `json:"entities"`
+	Registrations int `json:"registrations"`
+	Subscriptions int `json:"subscriptions"`
+}
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up Orion resources to an archive",
+	Long: `Back up Orion resources to an archive
+
+The entities, registrations and subscriptions of every tenant given with
+--tenants are written to a tar.gz archive of JSON files that restore can
+replay. A tenant is a Fiware service and service path written as
+SERVICE[:PATH], and defaults to the service and path given with -s and -P.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if archiveFile == "" {
+			return newUsageError("--file is required")
+		}
+		tenants, err := parseTenants(tenantNames)
+		if err != nil {
+			return err
+		}
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+
+		tmp, err := ioutil.TempFile(path.Dir(archiveFile), ".orionctl-backup-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		zw := gzip.NewWriter(tmp)
+		tw := tar.NewWriter(zw)
+
+		index := backupIndex{Created: time.Now().UTC().Format(orionTimeFormat)}
+		for _, t := range tenants {
+			bt, err := backupTenantResources(cmd.Context(), client, tw, t)
+			if err != nil {
+				tmp.Close()
+				return fmt.Errorf("%s: %w", t, err)
+			}
+			fmt.Fprintf(os.Stderr, "%s: %d entities, %d registrations, %d subscriptions\n", t, bt.Entities, bt.Registrations, bt.Subscriptions)
+			index.Tenants = append(index.Tenants, bt)
+		}
+		if err := addArchiveJSON(tw, "index.json", index); err != nil {
+			tmp.Close()
+			return err
+		}
+		for _, c := range []io.Closer{tw, zw, tmp} {
+			if err := c.Close(); err != nil {
+				return err
+			}
+		}
+		if err := os.Rename(tmp.Name(), archiveFile); err != nil {
+			return err
+		}
+		fmt.Printf("backup written to %s\n", archiveFile)
+		return nil
+	},
+}
+
+func backupTenantResources(ctx context.Context, client *orion.Client, tw *tar.Writer, t tenant) (backupTenant, error) {
+	bt := backupTenant{tenant: t}
+	entities, err := client.GetEntities(ctx, orion.EntityQuery{}, t.Service, t.ServicePath)
+	if err != nil {
+		return bt, err
+	}
+	registrations, err := backupResources(ctx, client.GetRawRegistrations, "Registration", t)
+	if err != nil {
+		return bt, err
+	}
+	subscriptions, err := backupResources(ctx, client.GetRawSubscriptions, "Subscription", t)
+	if err != nil {
+		return bt, err
+	}
+
+	files := []struct {
+		name string
+		v    interface{}
+	}{
+		{"entities.json", entities},
+		{"registrations.json", registrations},
+		{"subscriptions.json", subscriptions},
+	}
+	for _, f := range files {
+		if err := addArchiveJSON(tw, path.Join(t.dir(), f.name), f.v); err != nil {
+			return bt, err
+		}
+	}
+	bt.Entities, bt.Registrations, bt.Subscriptions = len(entities), len(registrations), len(subscriptions)
+	return bt, nil
+}
+
+// backupResources returns the subscriptions or registrations of t as Orion
+// returns them, without the fields Orion maintains.
+func backupResources(ctx context.Context, get func(context.Context, string, string) ([]json.RawMessage, error), kind string, t tenant) ([]map[string]interface{}, error) {
+	raw, err := get(ctx, t.Service, t.ServicePath)
+	if err != nil {
+		return nil, err
+	}
+	resources := make([]map[string]interface{}, 0, len(raw))
+	for _, item := range raw {
+		r, err := decodeResource(item)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, stripRuntime(kind, r))
+	}
+	return resources, nil
+}
+
+func addArchiveJSON(tw *tar.Writer, name string, v interface{}) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	b := buf.Bytes()
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(b)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(b)
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	backupCmd.Flags().StringVarP(&archiveFile, "file", "f", "", "Archive to write")
+	backupCmd.Flags().StringSliceVar(&tenantNames, "tenants", nil, "Comma separated list of SERVICE[:PATH] tenants to back up")
+}
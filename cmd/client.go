@@ -0,0 +1,214 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/YujiAzama/orionclient-go/orionclient"
+	"github.com/YujiAzama/orionctl/orion"
+)
+
+// newClient returns an Orion client for the configured broker.
+func newClient() (*orion.Client, error) {
+	if configErr != nil {
+		return nil, configErr
+	}
+	c := Context{
+		Server:             config.Server,
+		Host:               config.Host,
+		Port:               config.Port,
+		TLS:                &config.TLS,
+		Token:              config.Token,
+		Service:            fs,
+		ServicePath:        fsp,
+		CAFile:             caFile,
+		CertFile:           certFile,
+		KeyFile:            keyFile,
+		InsecureSkipVerify: insecureSkipVerify,
+		ServerName:         serverName,
+		Headers:            map[string]string{},
+	}
+	for name, value := range config.Headers {
+		c.Headers[name] = value
+	}
+	for _, h := range headers {
+		name, value, err := parseHeader(h)
+		if err != nil {
+			return nil, err
+		}
+		c.Headers[name] = value
+	}
+	if currentContext != nil {
+		c.Name, c.Auth = currentContext.Name, currentContext.Auth
+	}
+	return buildClient(c)
+}
+
+// newServerClient returns an Orion client for server, given as the name
+// of a context, or as host:port or an http(s) URL. A broker given by
+// address only gets the token given with --token, never the configured
+// one meant for another broker.
+func newServerClient(server string) (*orion.Client, error) {
+	if configErr != nil {
+		return nil, configErr
+	}
+	if ctx, ok := config.context(server); ok {
+		return buildClient(*ctx)
+	}
+	if !strings.Contains(server, "://") {
+		server = "http://" + server
+	}
+	c := Context{Server: server}
+	if rootCmd.PersistentFlags().Lookup("token").Changed {
+		c.Token = config.Token
+	}
+	return buildClient(c)
+}
+
+// buildClient returns a client for the broker of c whose requests go
+// through the transports configured by c.
+func buildClient(c Context) (*orion.Client, error) {
+	baseURL, err := c.baseURL()
+	if err != nil {
+		return nil, err
+	}
+	client, err := orion.NewClient(orionclient.ClientConfig{Token: c.Token})
+	if err != nil {
+		return nil, err
+	}
+	// orionclient only knows host and port, the base URL may also have a
+	// path prefix.
+	client.BaseURL = baseURL
+
+	tlsOptions := orion.TLSOptions{
+		CAFile:             c.CAFile,
+		CertFile:           c.CertFile,
+		KeyFile:            c.KeyFile,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		ServerName:         c.ServerName,
+	}
+	if !tlsOptions.IsZero() && baseURL.Scheme != "https" {
+		return nil, newUsageError("--ca-file, --cert-file, --key-file, --insecure-skip-verify and --server-name require --tls or an https --url")
+	}
+	base, err := orion.NewTransport(tlsOptions)
+	if err != nil {
+		return nil, &usageError{err: fmt.Errorf("tls: %w", err)}
+	}
+	var transport http.RoundTripper = base
+	if verbosity > 0 || dumpCurl {
+		trace := &orion.TraceTransport{Base: transport, Out: os.Stderr, Level: verbosity, Curl: dumpCurl, CurlArgs: curlTLSArgs(tlsOptions)}
+		for name := range c.Headers {
+			trace.SecretHeaders = append(trace.SecretHeaders, name)
+		}
+		transport = trace
+	}
+	if len(c.Headers) > 0 {
+		header := http.Header{}
+		for name, value := range c.Headers {
+			header.Set(name, value)
+		}
+		transport = &orion.HeaderTransport{Base: transport, Header: header}
+	}
+	if c.Auth != nil && c.Auth.Type != "" && c.Token == "" {
+		tokens, err := newTokenCache(&c, &http.Client{Transport: base, Timeout: timeout})
+		if err != nil {
+			return nil, err
+		}
+		transport = &orion.AuthTransport{Base: transport, Tokens: tokens}
+	}
+	if retries > 0 {
+		transport = &orion.RetryTransport{Base: transport, Retries: retries}
+	}
+	client.HTTPClient = &http.Client{Transport: transport, Timeout: timeout}
+	return client, nil
+}
+
+// curlTLSArgs returns the curl options matching the TLS options o.
+func curlTLSArgs(o orion.TLSOptions) []string {
+	var args []string
+	if o.CAFile != "" {
+		args = append(args, "--cacert", o.CAFile)
+	}
+	if o.CertFile != "" {
+		args = append(args, "--cert", o.CertFile, "--key", o.KeyFile)
+	}
+	if o.InsecureSkipVerify {
+		args = append(args, "--insecure")
+	}
+	return args
+}
+
+// parseHeader splits a header given as "Name: value".
+func parseHeader(h string) (string, string, error) {
+	i := strings.Index(h, ":")
+	if i <= 0 || strings.TrimSpace(h[:i]) == "" {
+		return "", "", newUsageError("invalid header \"%s\", expected \"Name: value\"", h)
+	}
+	return strings.TrimSpace(h[:i]), strings.TrimSpace(h[i+1:]), nil
+}
+
+// baseURL returns the URL of Orion: Server when it is set, and the URL
+// made of Host, Port and TLS otherwise.
+func (c Context) baseURL() (*url.URL, error) {
+	if c.Server == "" {
+		return url.Parse(c.server())
+	}
+	u, err := url.Parse(c.Server)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, newUsageError("invalid server \"%s\", expected http(s)://host[:port][/path]", c.Server)
+	}
+	return u, nil
+}
+
+// newTokenCache returns the token cache of the identity manager configured
+// by ctx, which is reached with client. Tokens are cached on disk per
+// context.
+func newTokenCache(ctx *Context, client *http.Client) (*orion.TokenCache, error) {
+	auth, service, servicePath := ctx.Auth, ctx.Service, ctx.ServicePath
+	if auth.URL == "" {
+		return nil, newUsageError("context \"%s\": auth.url is required", ctx.Name)
+	}
+	var source orion.TokenSource
+	switch auth.Type {
+	case "keystone":
+		domain := auth.Domain
+		if domain == "" {
+			domain = service
+		}
+		project := ""
+		if servicePath != "" && servicePath != "/" {
+			project = servicePath
+		}
+		source = &orion.Keystone{URL: auth.URL, Username: auth.Username, Password: auth.Password, Domain: domain, Project: project, Client: client}
+	case "keyrock":
+		source = &orion.Keyrock{URL: auth.URL, ClientID: auth.ClientID, ClientSecret: auth.ClientSecret, Username: auth.Username, Password: auth.Password, Client: client}
+	default:
+		return nil, newUsageError("context \"%s\": unknown auth.type \"%s\". One of: keystone, keyrock", ctx.Name, auth.Type)
+	}
+
+	key := strings.Join([]string{auth.Type, auth.URL, auth.Username, auth.ClientID, service, servicePath}, "|")
+	tokens := &orion.TokenCache{Source: source, Key: key}
+	if dir, err := os.UserCacheDir(); err == nil {
+		tokens.File = filepath.Join(dir, "orionctl", "tokens", ctx.Name+".json")
+	}
+	return tokens, nil
+}
@@ -0,0 +1,734 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Modify the config file",
+	Long: `Modify the config file
+
+Contexts name a broker together with the service and service path to use
+by default. The current context is used unless --context selects another
+one, and flags such as --host or --fiware-service override its settings.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Help()
+			os.Exit(0)
+		}
+	},
+}
+
+var getContextsCmd = &cobra.Command{
+	Use:   "get-contexts",
+	Short: "List the contexts of the config file",
+	Long:  "List the contexts of the config file",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := configPath()
+		if err != nil {
+			return err
+		}
+		c, err := loadConfigFile(file)
+		if err != nil {
+			return err
+		}
+
+		names := make([]string, 0, len(c.Contexts))
+		for _, ctx := range c.Contexts {
+			names = append(names, ctx.Name)
+		}
+		switch output {
+		case "", "wide", "name":
+		default:
+			return newUsageError("unknown output format \"%s\" for get-contexts. One of: wide, name", output)
+		}
+		return printResources(c.Contexts, false, names, func(wide bool) *uitable.Table {
+			table := uitable.New()
+			table.AddRow("Current", "Name", "Server", "Service", "Service Path")
+			for _, ctx := range c.Contexts {
+				current := ""
+				if ctx.Name == c.CurrentContext {
+					current = "*"
+				}
+				table.AddRow(current, ctx.Name, ctx.server(), ctx.Service, ctx.ServicePath)
+			}
+			return table
+		})
+	},
+}
+
+var currentContextCmd = &cobra.Command{
+	Use:   "current-context",
+	Short: "Print the current context",
+	Long:  "Print the current context",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := configPath()
+		if err != nil {
+			return err
+		}
+		c, err := loadConfigFile(file)
+		if err != nil {
+			return err
+		}
+		if c.CurrentContext == "" {
+			return fmt.Errorf("current context is not set in %s", file)
+		}
+		fmt.Println(c.CurrentContext)
+		return nil
+	},
+}
+
+var useContextCmd = &cobra.Command{
+	Use:   "use-context NAME",
+	Short: "Set the current context",
+	Long:  "Set the current context",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return newUsageError("requires exactly one context name")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := configPath()
+		if err != nil {
+			return err
+		}
+		c, err := loadConfigFile(file)
+		if err != nil {
+			return err
+		}
+		if _, ok := c.context(args[0]); !ok {
+			return newUsageError("context \"%s\" not found in %s", args[0], file)
+		}
+		c.CurrentContext = args[0]
+		if err := saveConfigFile(file, c); err != nil {
+			return err
+		}
+		fmt.Printf("Switched to context \"%s\".\n", args[0])
+		return nil
+	},
+}
+
+var setContextCmd = &cobra.Command{
+	Use:   "set-context NAME",
+	Short: "Create or modify a context",
+	Long: `Create or modify a context
+
+The context takes the settings given with --url, --host, --port, --tls,
+--token, --fiware-service, --fiware-servicepath, --header and the TLS flags
+--ca-file, --cert-file, --key-file, --insecure-skip-verify and
+--server-name. Settings that are not given are left unchanged, headers are
+added to the ones of the context. --host, --port and --tls remove the URL
+of the context.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return newUsageError("requires exactly one context name")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := configPath()
+		if err != nil {
+			return err
+		}
+		c, err := loadConfigFile(file)
+		if err != nil {
+			return err
+		}
+
+		action := "modified"
+		ctx, ok := c.context(args[0])
+		if !ok {
+			c.Contexts = append(c.Contexts, Context{Name: args[0]})
+			ctx = &c.Contexts[len(c.Contexts)-1]
+			action = "created"
+		}
+		flags := cmd.Flags()
+		if flags.Changed("url") {
+			if _, err := (Context{Server: serverURL}).baseURL(); err != nil {
+				return err
+			}
+			ctx.Server = serverURL
+		} else if addressFlagsChanged() {
+			ctx.Server = ""
+		}
+		if flags.Changed("host") {
+			ctx.Host = host
+		}
+		if flags.Changed("port") {
+			ctx.Port = port
+		}
+		if flags.Changed("tls") {
+			enabled := tls
+			ctx.TLS = &enabled
+		}
+		if flags.Changed("token") {
+			ctx.Token = token
+		}
+		if flags.Changed("fiware-service") {
+			ctx.Service = fs
+		}
+		if flags.Changed("fiware-servicepath") {
+			ctx.ServicePath = fsp
+		}
+		if flags.Changed("ca-file") {
+			ctx.CAFile = caFile
+		}
+		if flags.Changed("cert-file") {
+			ctx.CertFile = certFile
+		}
+		if flags.Changed("key-file") {
+			ctx.KeyFile = keyFile
+		}
+		if flags.Changed("insecure-skip-verify") {
+			ctx.InsecureSkipVerify = insecureSkipVerify
+		}
+		if flags.Changed("server-name") {
+			ctx.ServerName = serverName
+		}
+		for _, h := range headers {
+			name, value, err := parseHeader(h)
+			if err != nil {
+				return err
+			}
+			if ctx.Headers == nil {
+				ctx.Headers = map[string]string{}
+			}
+			ctx.Headers[name] = value
+		}
+		if err := saveConfigFile(file, c); err != nil {
+			return err
+		}
+		fmt.Printf("Context \"%s\" %s.\n", args[0], action)
+		return nil
+	},
+}
+
+var configRaw bool
+var configForce bool
+
+var viewConfigCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Print the config file",
+	Long: `Print the config file
+
+Tokens, passwords, client secrets and header values are redacted unless
+--raw is given.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := configPath()
+		if err != nil {
+			return err
+		}
+		c, err := loadConfigFile(file)
+		if err != nil {
+			return err
+		}
+		if !configRaw {
+			c = c.redacted()
+		}
+		switch output {
+		case "", "yaml":
+			b, err := yaml.Marshal(c)
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(b))
+		case "json":
+			b, err := json.MarshalIndent(c, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(b))
+		default:
+			return newUsageError("unknown output format \"%s\" for config view. One of: json, yaml", output)
+		}
+		return nil
+	},
+}
+
+var setConfigCmd = &cobra.Command{
+	Use:   "set KEY VALUE",
+	Short: "Set a value of the config file",
+	Long: `Set a value of the config file
+
+Keys of a context are written as contexts.NAME.KEY, and the context is
+created when it does not exist yet. For example:
+
+  orionctl config set port 1027
+  orionctl config set contexts.production.host orion.example.com
+  orionctl config set contexts.production.auth.type keyrock
+  orionctl config set contexts.production.headers.X-Api-Key 0c1f...`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 2 {
+			return newUsageError("requires a key and a value")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := configPath()
+		if err != nil {
+			return err
+		}
+		c, err := loadConfigFile(file)
+		if err != nil {
+			return err
+		}
+		if err := c.set(args[0], args[1]); err != nil {
+			return err
+		}
+		if err := saveConfigFile(file, c); err != nil {
+			return err
+		}
+		fmt.Printf("Property \"%s\" set.\n", args[0])
+		return nil
+	},
+}
+
+var initConfigCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Write a config file template",
+	Long: `Write a config file template
+
+The template documents every setting and is written to the file given
+with --config, or to $HOME/.orionctl.yaml.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file := cfgFile
+		if file == "" {
+			home, err := homedir.Dir()
+			if err != nil {
+				return err
+			}
+			file = filepath.Join(home, ".orionctl.yaml")
+		}
+		if _, err := os.Stat(file); err == nil && !configForce {
+			return newUsageError("%s already exists, use --force to overwrite it", file)
+		}
+		if err := ioutil.WriteFile(file, []byte(configTemplate), 0600); err != nil {
+			return err
+		}
+		fmt.Printf("config file %s written\n", file)
+		return nil
+	},
+}
+
+var validateConfigCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file",
+	Long:  "Check the config file for unknown keys and invalid values",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := configPath()
+		if err != nil {
+			return err
+		}
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		problems := validateConfig(b)
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", file, problem)
+		}
+		if len(problems) > 0 {
+			return newUsageError("%s: %d problems found", file, len(problems))
+		}
+		fmt.Printf("%s is valid\n", file)
+		return nil
+	},
+}
+
+const configTemplate = `# orionctl config file. Flags override every setting.
+
+# Broker used when no context is selected. server is the URL of Orion,
+# including any path prefix, and replaces host, port and tls.
+# server: https://gateway.example/orion/
+host: localhost
+port: 1026
+tls: false
+# token: ""
+
+# Context used by default. Select another one for a single command with
+# --context, or switch with "orionctl config use-context NAME".
+# current-context: staging
+
+# Contexts name a broker together with the service and service path used
+# when -s and -P are not given.
+# contexts:
+# - name: staging
+#   host: orion.staging.example.com
+#   port: 1026
+#   tls: false
+#   token: ""
+#   service: smartcity
+#   service-path: /parking
+#   # Headers sent with every request to Orion, e.g. API keys of a gateway.
+#   headers:
+#     X-Api-Key: ""
+#   # TLS settings, used with tls: true.
+#   ca-file: /etc/ssl/internal-ca.pem
+#   cert-file: ""
+#   key-file: ""
+#   insecure-skip-verify: false
+#   server-name: ""
+#   # Acquire tokens instead of using a fixed one. Keystone tokens are
+#   # scoped to the service (or auth.domain) and the service path.
+#   # Keyrock uses the OAuth2 password grant when a username is set and
+#   # the client credentials grant otherwise.
+#   auth:
+#     type: keystone
+#     url: http://keystone.example.com:5001
+#     username: admin
+#     password: ""
+#     # domain: smartcity
+#     # client-id: ""
+#     # client-secret: ""
+`
+
+// redacted returns a copy of c with its secrets replaced.
+func (c *Config) redacted() *Config {
+	r := *c
+	r.Token = redact(r.Token)
+	r.Headers = redactHeaders(r.Headers)
+	r.Contexts = make([]Context, len(c.Contexts))
+	for i, ctx := range c.Contexts {
+		ctx.Token = redact(ctx.Token)
+		ctx.Headers = redactHeaders(ctx.Headers)
+		if ctx.Auth != nil {
+			auth := *ctx.Auth
+			auth.Password = redact(auth.Password)
+			auth.ClientSecret = redact(auth.ClientSecret)
+			ctx.Auth = &auth
+		}
+		r.Contexts[i] = ctx
+	}
+	return &r
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "REDACTED"
+}
+
+// redactHeaders returns a copy of headers with their values redacted, as
+// gateways commonly take API keys as headers.
+func redactHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	r := make(map[string]string, len(headers))
+	for name, value := range headers {
+		r[name] = redact(value)
+	}
+	return r
+}
+
+// set sets the value of key, written as the YAML field names separated by
+// dots and as contexts.NAME.KEY for the keys of a context.
+func (c *Config) set(key string, value string) error {
+	path := strings.Split(key, ".")
+	if path[0] != "contexts" {
+		return setConfigField(reflect.ValueOf(c).Elem(), path, key, value)
+	}
+	if len(path) < 3 || path[1] == "" {
+		return newUsageError("invalid key \"%s\", expected contexts.NAME.KEY", key)
+	}
+	ctx, ok := c.context(path[1])
+	if !ok {
+		c.Contexts = append(c.Contexts, Context{Name: path[1]})
+		ctx = &c.Contexts[len(c.Contexts)-1]
+	}
+	return setConfigField(reflect.ValueOf(ctx).Elem(), path[2:], key, value)
+}
+
+func setConfigField(v reflect.Value, path []string, key string, value string) error {
+	for i := 0; i < v.NumField(); i++ {
+		if yamlName(v.Type().Field(i)) != path[0] {
+			continue
+		}
+		field := v.Field(i)
+		if field.Kind() == reflect.Ptr && len(path) > 1 {
+			if field.IsNil() {
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+			field = field.Elem()
+		}
+		if field.Kind() == reflect.Struct && len(path) > 1 {
+			return setConfigField(field, path[1:], key, value)
+		}
+		if field.Kind() == reflect.Map && len(path) == 2 && path[1] != "" {
+			if field.IsNil() {
+				field.Set(reflect.MakeMap(field.Type()))
+			}
+			field.SetMapIndex(reflect.ValueOf(path[1]), reflect.ValueOf(value))
+			return nil
+		}
+		if len(path) > 1 {
+			break
+		}
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+			field = field.Elem()
+		}
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(value)
+			return nil
+		case reflect.Int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return newUsageError("%s must be a number, not \"%s\"", key, value)
+			}
+			field.SetInt(int64(n))
+			return nil
+		case reflect.Bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return newUsageError("%s must be true or false, not \"%s\"", key, value)
+			}
+			field.SetBool(b)
+			return nil
+		}
+		break
+	}
+	return newUsageError("unknown key \"%s\". One of: %s", key, strings.Join(configKeys(reflect.TypeOf(Config{}), ""), ", "))
+}
+
+// configKeys lists the keys accepted by Config.set.
+func configKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := prefix + yamlName(field)
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch ft.Kind() {
+		case reflect.Struct:
+			keys = append(keys, configKeys(ft, name+".")...)
+		case reflect.Slice:
+			keys = append(keys, configKeys(ft.Elem(), name+".NAME.")...)
+		case reflect.Map:
+			keys = append(keys, name+".NAME")
+		default:
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+func yamlName(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("yaml"), ",")[0]
+}
+
+// validateConfig returns the problems found in the content of a config
+// file. Keys are matched case-insensitively, as they are when the file is
+// loaded.
+func validateConfig(b []byte) []string {
+	var doc interface{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return []string{err.Error()}
+	}
+	lowered, err := yaml.Marshal(lowerKeys(doc))
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var c Config
+	var problems []string
+	if err := yaml.UnmarshalStrict(lowered, &c); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return []string{err.Error()}
+		}
+		for _, problem := range typeErr.Errors {
+			// Line numbers refer to the lowercased document, not the file.
+			problem = yamlLine.ReplaceAllString(problem, "")
+			problem = yamlUnknownField.ReplaceAllString(problem, "unknown key \"$1\"")
+			problems = append(problems, problem)
+		}
+	}
+
+	if c.Port < 0 || c.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("port %d is out of range", c.Port))
+	}
+	if _, err := (Context{Server: c.Server}).baseURL(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	names := map[string]bool{}
+	for i, ctx := range c.Contexts {
+		if ctx.Name == "" {
+			problems = append(problems, fmt.Sprintf("context %d has no name", i+1))
+			continue
+		}
+		if names[ctx.Name] {
+			problems = append(problems, fmt.Sprintf("context \"%s\" is defined more than once", ctx.Name))
+		}
+		names[ctx.Name] = true
+		if ctx.Port < 0 || ctx.Port > 65535 {
+			problems = append(problems, fmt.Sprintf("context \"%s\": port %d is out of range", ctx.Name, ctx.Port))
+		}
+		if _, err := (Context{Server: ctx.Server}).baseURL(); err != nil {
+			problems = append(problems, fmt.Sprintf("context \"%s\": %s", ctx.Name, err))
+		}
+		if ctx.ServicePath != "" && !strings.HasPrefix(ctx.ServicePath, "/") {
+			problems = append(problems, fmt.Sprintf("context \"%s\": service-path must start with /", ctx.Name))
+		}
+		if auth := ctx.Auth; auth != nil && auth.Type != "" {
+			switch {
+			case auth.Type != "keystone" && auth.Type != "keyrock":
+				problems = append(problems, fmt.Sprintf("context \"%s\": auth.type must be keystone or keyrock", ctx.Name))
+			case auth.URL == "":
+				problems = append(problems, fmt.Sprintf("context \"%s\": auth.url is required", ctx.Name))
+			case auth.Type == "keystone" && auth.Username == "":
+				problems = append(problems, fmt.Sprintf("context \"%s\": auth.username is required by keystone", ctx.Name))
+			case auth.Type == "keyrock" && auth.ClientID == "":
+				problems = append(problems, fmt.Sprintf("context \"%s\": auth.client-id is required by keyrock", ctx.Name))
+			}
+		}
+	}
+	if c.CurrentContext != "" && !names[c.CurrentContext] {
+		problems = append(problems, fmt.Sprintf("current-context \"%s\" is not defined", c.CurrentContext))
+	}
+	return problems
+}
+
+var yamlLine = regexp.MustCompile(`^line \d+: `)
+var yamlUnknownField = regexp.MustCompile(`field (\S+) not found in type \S+`)
+
+func lowerKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := map[interface{}]interface{}{}
+		for key, value := range v {
+			m[strings.ToLower(fmt.Sprint(key))] = lowerKeys(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range v {
+			v[i] = lowerKeys(value)
+		}
+	}
+	return v
+}
+
+// server returns the URL of the broker of the context.
+func (c Context) server() string {
+	if c.Server != "" {
+		return c.Server
+	}
+	scheme, host, port := "http", c.Host, c.Port
+	if c.TLS != nil && *c.TLS {
+		scheme = "https"
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	if port == 0 {
+		port = 1026
+	}
+	return scheme + "://" + host + ":" + strconv.Itoa(port)
+}
+
+// configPath returns the config file given with --config, the one that
+// was found in the home directory, or the default location when there is
+// none yet.
+func configPath() (string, error) {
+	if cfgFile != "" {
+		return cfgFile, nil
+	}
+	if file := viper.ConfigFileUsed(); file != "" {
+		return file, nil
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".orionctl.yaml"), nil
+}
+
+// loadConfigFile reads file without the settings given as flags. A
+// missing file is an empty config.
+func loadConfigFile(file string) (*Config, error) {
+	c := &Config{}
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return c, nil
+	}
+	v := viper.New()
+	v.SetConfigFile(file)
+	if filepath.Ext(file) == "" {
+		v.SetConfigType("yaml")
+	}
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	if err := v.Unmarshal(c); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return c, nil
+}
+
+// saveConfigFile writes c to file as YAML, or as JSON when file has a
+// .json extension. The file is only readable by the user as it may hold
+// tokens and passwords.
+func saveConfigFile(file string, c *Config) error {
+	var b []byte
+	var err error
+	if filepath.Ext(file) == ".json" {
+		b, err = json.MarshalIndent(c, "", "  ")
+	} else {
+		b, err = yaml.Marshal(c)
+	}
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, b, 0600)
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(getContextsCmd)
+	configCmd.AddCommand(currentContextCmd)
+	configCmd.AddCommand(useContextCmd)
+	configCmd.AddCommand(setContextCmd)
+	viewConfigCmd.Flags().BoolVar(&configRaw, "raw", false, "Print secrets instead of redacting them")
+	configCmd.AddCommand(viewConfigCmd)
+	setConfigCmd.Long += "\n\nKeys:\n  " + strings.Join(configKeys(reflect.TypeOf(Config{}), ""), "\n  ")
+	configCmd.AddCommand(setConfigCmd)
+	initConfigCmd.Flags().BoolVar(&configForce, "force", false, "Overwrite an existing config file")
+	configCmd.AddCommand(initConfigCmd)
+	configCmd.AddCommand(validateConfigCmd)
+}
@@ -0,0 +1,289 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/YujiAzama/orionctl/orion"
+)
+
+var copyFrom string
+var copyTo string
+var serviceMap map[string]string
+var servicePathMap map[string]string
+var hostMap map[string]string
+
+var copyCmd = &cobra.Command{
+	Use:   "copy",
+	Short: "Copy Orion resources between brokers",
+	Long: `Copy Orion resources between brokers
+
+The entities, registrations and subscriptions of every tenant given with
+--tenants are copied from the broker given with --from to the one given
+with --to. Brokers are given as the name of a context, as host:port or as
+a URL. Without --tenants, the tenant given with -s and -P or else the one
+of the --from context is copied. Brokers given as host:port or URL only
+get the token given with --token. Existing resources are never changed,
+so copy can be run repeatedly:
+
+  Entity        created unless one with the same id and type exists
+  Subscription  created unless an identical one exists
+  Registration  created unless an identical one exists
+
+Entities that exist with different attributes are reported and left as
+they are.
+
+The target service, service path and the host of notification and
+provider URLs can be rewritten with mapping rules:
+
+  --map-service staging=production
+  --map-service-path /staging=/     (also maps /staging/parking to /parking)
+  --map-host receiver.staging:1028=receiver.production:1028`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if copyFrom == "" || copyTo == "" {
+			return newUsageError("--from and --to are required")
+		}
+		tenants := []tenant{defaultCopyTenant()}
+		if len(tenantNames) > 0 {
+			var err error
+			if tenants, err = parseTenants(tenantNames); err != nil {
+				return err
+			}
+		}
+		for from, to := range servicePathMap {
+			if !strings.HasPrefix(from, "/") || !strings.HasPrefix(to, "/") {
+				return newUsageError("invalid --map-service-path \"%s=%s\", service paths must be absolute", from, to)
+			}
+		}
+		source, err := newServerClient(copyFrom)
+		if err != nil {
+			return err
+		}
+		target, err := newServerClient(copyTo)
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, t := range tenants {
+			mapped := mapTenant(t)
+			fmt.Printf("%s -> %s\n", t, mapped)
+			n, err := copyTenant(cmd.Context(), source, target, t, mapped)
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				fmt.Fprintf(os.Stderr, "Error: %s: %d resources could not be copied\n", t, n)
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d tenants could not be copied completely", failed, len(tenants))
+		}
+		return nil
+	},
+}
+
+// defaultCopyTenant returns the tenant copied without --tenants: the one
+// given with -s and -P, or else the one of the --from context rather than
+// the one of the current context.
+func defaultCopyTenant() tenant {
+	t := tenant{ServicePath: "/"}
+	if ctx, ok := config.context(copyFrom); ok {
+		t.Service = ctx.Service
+		if ctx.ServicePath != "" {
+			t.ServicePath = ctx.ServicePath
+		}
+	}
+	flags := rootCmd.PersistentFlags()
+	if flags.Lookup("fiware-service").Changed {
+		t.Service = fs
+	}
+	if flags.Lookup("fiware-servicepath").Changed && fsp != "" {
+		t.ServicePath = fsp
+	}
+	return t
+}
+
+// copyTenant copies the resources of t on source to mapped on target,
+// rewriting their URLs by the host mapping rules. Subscriptions and
+// registrations are copied as Orion returns them, so that fields
+// orionclient does not model are kept. It returns the number of resources
+// that could not be copied.
+func copyTenant(ctx context.Context, source, target *orion.Client, t, mapped tenant) (int, error) {
+	failed := 0
+	report := func(kind, name, action string, err error) {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s \"%s\": %v\n", strings.ToLower(kind), name, err)
+			failed++
+			return
+		}
+		fmt.Printf("%s \"%s\" %s\n", strings.ToLower(kind), name, action)
+	}
+
+	entities, err := source.GetEntities(ctx, orion.EntityQuery{}, t.Service, t.ServicePath)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", t, err)
+	}
+	for _, entity := range entities {
+		action, err := copyEntity(ctx, target, mapped, *entity)
+		report("Entity", entityKey(entity), action, err)
+	}
+
+	for _, kind := range []string{"Registration", "Subscription"} {
+		resources, err := getRawResources(ctx, source, kind, t)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", t, err)
+		}
+		live, err := getRawResources(ctx, target, kind, mapped)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", mapped, err)
+		}
+		existing := map[string]int{}
+		for _, item := range live {
+			r, err := decodeResource(item)
+			if err != nil {
+				return 0, err
+			}
+			existing[contentKey(kind, r)]++
+		}
+
+		for _, item := range resources {
+			r, err := decodeResource(item)
+			if err != nil {
+				return 0, err
+			}
+			name := stringField(r, "description")
+			if name == "" {
+				name = stringField(r, "id")
+			}
+			stripRuntime(kind, r)
+			for _, path := range []string{"notification.http.url", "notification.httpCustom.url", "notification.mqtt.url", "provider.http.url"} {
+				if u := stringField(r, path); u != "" {
+					setField(r, path, mapHost(u))
+				}
+			}
+			// Identical resources are matched one to one, so that
+			// duplicates on the source are duplicated on the target.
+			if key := contentKey(kind, r); existing[key] > 0 {
+				existing[key]--
+				report(kind, name, "unchanged", nil)
+				continue
+			}
+			if kind == "Registration" {
+				_, err = target.CreateRegistration(ctx, r, mapped.Service, mapped.ServicePath)
+			} else {
+				_, err = target.CreateSubscription(ctx, r, mapped.Service, mapped.ServicePath)
+			}
+			report(kind, name, "created", err)
+		}
+	}
+
+	return failed, nil
+}
+
+// copyEntity creates entity on t unless an entity with the same ID and
+// type exists, which is left as it is.
+func copyEntity(ctx context.Context, client *orion.Client, t tenant, entity orion.Entity) (string, error) {
+	current, err := client.GetEntity(ctx, entity.Id, entity.Type, t.Service, t.ServicePath)
+	if isNotFound(err) {
+		_, err := client.CreateEntity(ctx, entity, t.Service, t.ServicePath)
+		return "created", err
+	}
+	if err != nil {
+		return "", err
+	}
+	if sameJSON(normalizeEntity(entity), normalizeEntity(*current)) {
+		return "unchanged", nil
+	}
+	return "differs from the source, left unchanged", nil
+}
+
+func getRawResources(ctx context.Context, client *orion.Client, kind string, t tenant) ([]json.RawMessage, error) {
+	if kind == "Registration" {
+		return client.GetRawRegistrations(ctx, t.Service, t.ServicePath)
+	}
+	return client.GetRawSubscriptions(ctx, t.Service, t.ServicePath)
+}
+
+// contentKey identifies a subscription or registration by its normalized
+// content and whether it is paused.
+func contentKey(kind string, r map[string]interface{}) string {
+	n := normalizeResource(kind, r)
+	if r["status"] == "inactive" {
+		n["status"] = "inactive"
+	}
+	b, _ := json.Marshal(n)
+	return string(b)
+}
+
+// mapTenant returns the target tenant of t according to the service and
+// service path mapping rules. The longest matching service path wins.
+func mapTenant(t tenant) tenant {
+	if service, ok := serviceMap[t.Service]; ok {
+		t.Service = service
+	}
+	match, target := "", ""
+	for from, to := range servicePathMap {
+		from = path.Clean(from)
+		if (t.ServicePath == from || strings.HasPrefix(t.ServicePath, strings.TrimSuffix(from, "/")+"/")) && len(from) > len(match) {
+			match, target = from, to
+		}
+	}
+	if match != "" {
+		t.ServicePath = path.Join(target, strings.TrimPrefix(t.ServicePath, match))
+	}
+	return t
+}
+
+// mapHost rewrites the host of rawURL according to the host mapping rules,
+// which match either host:port or the host name alone.
+func mapHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	if host, ok := hostMap[u.Host]; ok {
+		u.Host = host
+		return u.String()
+	}
+	if host, ok := hostMap[u.Hostname()]; ok {
+		if u.Port() != "" && !strings.Contains(host, ":") {
+			host += ":" + u.Port()
+		}
+		u.Host = host
+		return u.String()
+	}
+	return rawURL
+}
+
+func init() {
+	rootCmd.AddCommand(copyCmd)
+	copyCmd.Flags().StringVar(&copyFrom, "from", "", "Broker to copy from, as a context name, host:port or URL")
+	copyCmd.Flags().StringVar(&copyTo, "to", "", "Broker to copy to, as a context name, host:port or URL")
+	copyCmd.Flags().StringSliceVar(&tenantNames, "tenants", nil, "Comma separated list of SERVICE[:PATH] tenants to copy")
+	copyCmd.Flags().StringToStringVar(&serviceMap, "map-service", nil, "Copy a service to another one, as FROM=TO")
+	copyCmd.Flags().StringToStringVar(&servicePathMap, "map-service-path", nil, "Copy a service path and its children to another one, as FROM=TO")
+	copyCmd.Flags().StringToStringVar(&hostMap, "map-host", nil, "Rewrite the host of notification and provider URLs, as FROM=TO")
+}
@@ -16,23 +16,97 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/YujiAzama/orionctl/orion"
 )
 
+var filename string
+var recursive bool
+
 var createCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Create Orion resources",
-	Long:  "Create Orion resources",
-	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) == 0 {
+	Long: `Create Orion resources
+
+Resources are read from the file, directory or URL given with -f. Every
+document must declare its kind (Entity, Registration or Subscription) and
+documents are created in that order.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && filename == "" {
 			cmd.Help()
 			os.Exit(0)
 		}
+		manifests, err := readManifests(filename, recursive)
+		if err != nil {
+			return err
+		}
+		for _, m := range manifests {
+			if m.Kind == "" {
+				return newUsageError("%s: kind is required, must be one of %s", m.Source, strings.Join(manifestKinds, ", "))
+			}
+		}
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		return createManifests(cmd.Context(), client, manifests)
 	},
 }
 
+// createManifests creates every manifest in dependency order, reporting
+// the result of each document. When several documents are created, a
+// failure does not stop the remaining ones.
+func createManifests(ctx context.Context, client *orion.Client, manifests []manifest) error {
+	sortManifests(manifests)
+	var failed []error
+	for _, m := range manifests {
+		id, err := createManifest(ctx, client, m)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", m.Source, err)
+			if len(manifests) > 1 {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+			failed = append(failed, err)
+			continue
+		}
+		fmt.Printf("%s \"%s\" created\n", strings.ToLower(m.Kind), id)
+	}
+	switch {
+	case len(failed) == 0:
+		return nil
+	case len(manifests) == 1:
+		return failed[0]
+	default:
+		return fmt.Errorf("%d of %d resources could not be created", len(failed), len(manifests))
+	}
+}
+
+func createManifest(ctx context.Context, client *orion.Client, m manifest) (string, error) {
+	switch m.Kind {
+	case "Entity":
+		var entity orion.Entity
+		if err := m.decode(&entity); err != nil {
+			return "", err
+		}
+		return client.CreateEntity(ctx, entity, fs, fsp)
+	// Registrations and subscriptions are sent as written, so that fields
+	// orionclient does not model, such as httpCustom, reach Orion.
+	case "Registration":
+		return client.CreateRegistration(ctx, json.RawMessage(m.Data), fs, fsp)
+	case "Subscription":
+		return client.CreateSubscription(ctx, json.RawMessage(m.Data), fs, fsp)
+	}
+	return "", newUsageError("unknown kind \"%s\"", m.Kind)
+}
+
 func init() {
 	rootCmd.AddCommand(createCmd)
+	createCmd.Flags().StringVarP(&filename, "filename", "f", "", "Resource file, directory, URL or - for stdin")
+	createCmd.PersistentFlags().BoolVarP(&recursive, "recursive", "R", false, "Process the directory given with -f recursively")
 }
@@ -0,0 +1,285 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/YujiAzama/orionctl/orion"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Diff manifests against the live resources",
+	Long: `Diff manifests against the live resources
+
+Every document of the files given with -f is matched to a live resource the
+same way as apply does, and the differences are printed as a unified diff.
+Fields maintained by Orion, such as the subscription status and
+notification statistics, are ignored.
+
+diff exits with status 6 when differences are found.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manifests, err := readManifests(filename, recursive)
+		if err != nil {
+			return err
+		}
+		for _, m := range manifests {
+			if m.Kind == "" {
+				return newUsageError("%s: kind is required, must be one of %s", m.Source, strings.Join(manifestKinds, ", "))
+			}
+		}
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		live, err := loadLiveState(cmd.Context(), client, currentTenant())
+		if err != nil {
+			return err
+		}
+
+		sortManifests(manifests)
+		drift := false
+		for _, m := range manifests {
+			name, current, desired, err := diffManifest(cmd.Context(), client, live, m)
+			if err != nil {
+				return fmt.Errorf("%s: %w", m.Source, err)
+			}
+			from, err := toYAMLString(current)
+			if err != nil {
+				return err
+			}
+			to, err := toYAMLString(desired)
+			if err != nil {
+				return err
+			}
+			lines := unifiedDiff(
+				"live/"+strings.ToLower(m.Kind)+"/"+name, m.Source,
+				strings.SplitAfter(from, "\n"), strings.SplitAfter(to, "\n"))
+			if len(lines) > 0 {
+				drift = true
+				printDiff(lines)
+			}
+		}
+		if drift {
+			return &exitStatus{code: exitDrift}
+		}
+		return nil
+	},
+}
+
+// diffManifest returns the normalized live resource matching m and the
+// normalized manifest. The live resource is nil when nothing matches m.
+func diffManifest(ctx context.Context, client *orion.Client, live *liveState, m manifest) (string, interface{}, interface{}, error) {
+	switch m.Kind {
+	case "Subscription":
+		var desired map[string]interface{}
+		if err := m.decode(&desired); err != nil {
+			return "", nil, nil, err
+		}
+		key := subscriptionKey(desired)
+		if key == "" {
+			return "", nil, nil, newUsageError("a description is required to apply a subscription")
+		}
+		current, err := live.subscription(key)
+		if err != nil || current == nil {
+			return key, nil, normalizeResource(m.Kind, desired), err
+		}
+		d, l := normalizeResourcePair(m.Kind, desired, current)
+		return stringField(current, "id"), l, d, nil
+	case "Registration":
+		var desired map[string]interface{}
+		if err := m.decode(&desired); err != nil {
+			return "", nil, nil, err
+		}
+		key := registrationKey(desired)
+		current, err := live.registration(key)
+		if err != nil || current == nil {
+			return key, nil, normalizeResource(m.Kind, desired), err
+		}
+		d, l := normalizeResourcePair(m.Kind, desired, current)
+		return stringField(current, "id"), l, d, nil
+	case "Entity":
+		var desired orion.Entity
+		if err := m.decode(&desired); err != nil {
+			return "", nil, nil, err
+		}
+		key := entityKey(&desired)
+		current, err := client.GetEntity(ctx, desired.Id, desired.Type, live.tenant.Service, live.tenant.ServicePath)
+		if isNotFound(err) {
+			return key, nil, normalizeEntity(desired), nil
+		}
+		if err != nil {
+			return key, nil, nil, err
+		}
+		return key, normalizeEntity(*current), normalizeEntity(desired), nil
+	}
+	return "", nil, nil, newUsageError("unknown kind \"%s\"", m.Kind)
+}
+
+func toYAMLString(v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	b, err := toYAML(v)
+	return string(b), err
+}
+
+func printDiff(lines []string) {
+	header := color.New(color.Bold)
+	hunk := color.New(color.FgCyan)
+	removed := color.New(color.FgRed)
+	added := color.New(color.FgGreen)
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			header.Println(line)
+		case strings.HasPrefix(line, "@@"):
+			hunk.Println(line)
+		case strings.HasPrefix(line, "-"):
+			removed.Println(line)
+		case strings.HasPrefix(line, "+"):
+			added.Println(line)
+		default:
+			fmt.Println(line)
+		}
+	}
+}
+
+// unifiedDiff returns the unified diff of two texts split into lines, with
+// three lines of context, or nothing when they are equal.
+func unifiedDiff(fromName, toName string, from, to []string) []string {
+	from, to = trimEmpty(from), trimEmpty(to)
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// from[i:] and to[j:].
+	lcs := make([][]int, len(from)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	type edit struct {
+		op   byte
+		line string
+		i, j int
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(from) || j < len(to) {
+		switch {
+		case i < len(from) && j < len(to) && from[i] == to[j]:
+			edits = append(edits, edit{' ', from[i], i, j})
+			i++
+			j++
+		case i < len(from) && (j == len(to) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', from[i], i, j})
+			i++
+		default:
+			edits = append(edits, edit{'+', to[j], i, j})
+			j++
+		}
+	}
+
+	const context = 3
+	var lines []string
+	for k := 0; k < len(edits); {
+		if edits[k].op == ' ' {
+			k++
+			continue
+		}
+		start := k - context
+		if start < 0 {
+			start = 0
+		}
+		end := k
+		for end < len(edits) {
+			if edits[end].op != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(edits) && edits[next].op == ' ' {
+				next++
+			}
+			if next == len(edits) || next-end > 2*context {
+				break
+			}
+			end = next
+		}
+		stop := end + context
+		if stop > len(edits) {
+			stop = len(edits)
+		}
+
+		fromCount, toCount := 0, 0
+		for _, e := range edits[start:stop] {
+			if e.op != '+' {
+				fromCount++
+			}
+			if e.op != '-' {
+				toCount++
+			}
+		}
+		if len(lines) == 0 {
+			lines = append(lines, "--- "+fromName, "+++ "+toName)
+		}
+		lines = append(lines, fmt.Sprintf("@@ -%s +%s @@",
+			hunkRange(edits[start].i, fromCount), hunkRange(edits[start].j, toCount)))
+		for _, e := range edits[start:stop] {
+			lines = append(lines, string(e.op)+strings.TrimSuffix(e.line, "\n"))
+		}
+		k = stop
+	}
+	return lines
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func trimEmpty(lines []string) []string {
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().StringVarP(&filename, "filename", "f", "", "Resource file, directory, URL or - for stdin")
+	diffCmd.Flags().BoolVarP(&recursive, "recursive", "R", false, "Process the directory given with -f recursively")
+}
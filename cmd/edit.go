@@ -0,0 +1,220 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+	"github.com/YujiAzama/orionctl/orion"
+)
+
+const editHeader = `# Please edit the object below. Lines beginning with a '#' will be ignored,
+# and an empty file will abort the edit. If an error occurs while saving this
+# file will be reopened with the relevant failures.
+#
+`
+
+var editCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit Orion resources",
+	Long: `Edit Orion resources
+
+The resource is opened as YAML in the editor named by the EDITOR
+environment variable, or vi when it is not set. Saving the file applies
+the changes; fields maintained by Orion are left out.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Help()
+			os.Exit(0)
+		}
+	},
+}
+
+// editResource opens resource in the editor, without the fields listed in
+// readOnly by their dotted path, and passes the edited document, encoded as JSON, to
+// save. When save fails with an error from Orion or an invalid document,
+// the editor is opened again with the error at the top of the file.
+// Saving an unchanged or empty file cancels the edit.
+func editResource(name string, resource interface{}, readOnly []string, save func(data []byte) error) error {
+	original, err := editableYAML(resource, readOnly)
+	if err != nil {
+		return err
+	}
+	content, comment := original, ""
+	var failed []byte
+	for {
+		edited, err := runEditor(name, []byte(editHeader+comment+string(content)))
+		if err != nil {
+			return err
+		}
+		edited = stripComments(edited)
+		if len(bytes.TrimSpace(edited)) == 0 {
+			fmt.Println("Edit cancelled, the file is empty")
+			return nil
+		}
+		if bytes.Equal(edited, original) {
+			fmt.Println("Edit cancelled, no changes made")
+			return nil
+		}
+		if failed != nil && bytes.Equal(edited, failed) {
+			return errors.New("edit cancelled, the changes are still invalid")
+		}
+
+		data, err := yamlToJSON(edited)
+		if err == nil {
+			err = save(data)
+		}
+		if err == nil {
+			return nil
+		}
+		var orionErr *orion.Error
+		var usageErr *usageError
+		if !errors.As(err, &orionErr) && !errors.As(err, &usageErr) {
+			return err
+		}
+		content, comment, failed = edited, errorComment(err), edited
+	}
+}
+
+// editableYAML encodes v as YAML without the given fields.
+func editableYAML(v interface{}, readOnly []string) ([]byte, error) {
+	doc, err := orderedFields(v, readOnly)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
+
+// editing is 1 while an editor runs.
+var editing int32
+
+func runEditor(name string, content []byte) ([]byte, error) {
+	file, err := ioutil.TempFile("", "orionctl-edit-"+name+"-*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+	cmd := exec.Command(editor[0], append(editor[1:], file.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	atomic.StoreInt32(&editing, 1)
+	defer atomic.StoreInt32(&editing, 0)
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("editor %s: %w", editor[0], err)
+	}
+	return ioutil.ReadFile(file.Name())
+}
+
+// stripComments removes the comment block at the top of content, which
+// holds editHeader and the errors of the previous attempt. Comments further
+// down are left to the YAML parser, since a line of a block scalar may
+// start with a '#' as well.
+func stripComments(content []byte) []byte {
+	rest := string(content)
+	for rest != "" {
+		line := rest
+		if i := strings.IndexByte(rest, '\n'); i >= 0 {
+			line = rest[:i+1]
+		}
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			break
+		}
+		rest = rest[len(line):]
+	}
+	return []byte(rest)
+}
+
+func errorComment(err error) string {
+	comment := ""
+	for _, line := range strings.Split(err.Error(), "\n") {
+		comment += "# error: " + line + "\n"
+	}
+	return comment + "#\n"
+}
+
+func yamlToJSON(content []byte) ([]byte, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, &usageError{err: err}
+	}
+	object, ok := convertYAML(doc).(map[string]interface{})
+	if !ok {
+		return nil, newUsageError("the edited document must be an object")
+	}
+	return json.Marshal(object)
+}
+
+// readOnlyFields are the fields of a subscription or registration that
+// are maintained by Orion and left out of the editor. The status can be
+// edited.
+func readOnlyFields(kind string) []string {
+	var fields []string
+	for _, field := range runtimeFields(kind) {
+		if field != "status" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// editableResource decodes a subscription or registration as Orion returns
+// it, without its read only fields.
+func editableResource(kind string, raw json.RawMessage) (map[string]interface{}, error) {
+	r, err := decodeResource(raw)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range readOnlyFields(kind) {
+		deleteField(r, field)
+	}
+	return r, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	rootCmd.AddCommand(editCmd)
+}
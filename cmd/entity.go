@@ -0,0 +1,377 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+	"github.com/YujiAzama/orionctl/orion"
+)
+
+var entityFile string
+var entityType string
+var entityQuery orion.EntityQuery
+var near string
+var maxDistance float64
+var minDistance float64
+
+var getEntityCmd = &cobra.Command{
+	Use:     "entities",
+	Aliases: []string{"entity", "ent"},
+	Short:   "Get entity. Aliases: [\"entity\", \"ent\"]",
+	Long:    "Get entity",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+
+		if near != "" {
+			if entityQuery.Georel != "" || entityQuery.Geometry != "" || entityQuery.Coords != "" {
+				return newUsageError("--near cannot be combined with --georel, --geometry or --coords")
+			}
+			if maxDistance == 0 && minDistance == 0 {
+				return newUsageError("--near requires --max-distance or --min-distance")
+			}
+			entityQuery.Georel = orion.NearGeorel(maxDistance, minDistance)
+			entityQuery.Geometry = "point"
+			entityQuery.Coords = near
+		}
+		maxResults, err := pageLimit()
+		if err != nil {
+			return err
+		}
+
+		var entities = []*orion.Entity{}
+		total := 0
+		if len(args) > 0 {
+			for _, id := range args {
+				entity, err := client.GetEntity(cmd.Context(), id, entityType, fs, fsp)
+				if err != nil {
+					return fmt.Errorf("entity \"%s\": %w", id, err)
+				}
+				entities = append(entities, entity)
+			}
+		} else {
+			entityQuery.Type = entityType
+			total, err = orion.Paginate(maxResults, offset, func(page orion.Page) (int, int, error) {
+				items, count, err := client.GetEntitiesPage(cmd.Context(), entityQuery, page, fs, fsp)
+				entities = append(entities, items...)
+				return len(items), count, err
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if output == "geojson" {
+			b, err := json.MarshalIndent(toFeatureCollection(entities), "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(b))
+			return nil
+		}
+
+		ids := make([]string, 0, len(entities))
+		for _, entity := range entities {
+			ids = append(ids, entity.Id)
+		}
+		err = printResources(entities, len(args) == 1, ids, func(wide bool) *uitable.Table {
+			return entityTable(entities, wide)
+		})
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 && isTableOutput() {
+			printTotal(len(entities), total)
+		}
+		return nil
+	},
+}
+
+var describeEntityCmd = &cobra.Command{
+	Use:     "entities",
+	Aliases: []string{"entity", "ent"},
+	Short:   "Describe entity. Aliases: [\"entity\", \"ent\"]",
+	Long:    "Describe entity",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+
+		var entities = []*orion.Entity{}
+		if len(args) > 0 {
+			for _, id := range args {
+				entity, err := client.GetEntity(cmd.Context(), id, entityType, fs, fsp)
+				if err != nil {
+					return fmt.Errorf("entity \"%s\": %w", id, err)
+				}
+				entities = append(entities, entity)
+			}
+		} else {
+			allEntities, err := client.GetEntities(cmd.Context(), orion.EntityQuery{Type: entityType}, fs, fsp)
+			if err != nil {
+				return err
+			}
+			entities = allEntities
+		}
+
+		ids := make([]string, 0, len(entities))
+		for _, entity := range entities {
+			ids = append(ids, entity.Id)
+		}
+		err = printResources(entities, len(args) == 1, ids, func(wide bool) *uitable.Table {
+			return describeEntityTable(entities)
+		})
+		if err != nil {
+			return err
+		}
+		return nil
+	},
+}
+
+var createEntityCmd = &cobra.Command{
+	Use:     "entities",
+	Aliases: []string{"entity", "ent"},
+	Short:   "Create entity. Aliases: [\"entity\", \"ent\"]",
+	Long:    "Create entity resources by filename",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manifests, err := readManifests(entityFile, recursive)
+		if err != nil {
+			return err
+		}
+		if err := requireKind(manifests, "Entity"); err != nil {
+			return err
+		}
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		return createManifests(cmd.Context(), client, manifests)
+	},
+}
+
+var deleteEntityCmd = &cobra.Command{
+	Use:     "entities",
+	Aliases: []string{"entity", "ent"},
+	Short:   "Delete entity. Aliases: [\"entity\", \"ent\"]",
+	Long:    "Delete entity",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return newUsageError("requires an entity ID")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		for _, entityId := range args {
+			if err := client.DeleteEntity(cmd.Context(), entityId, entityType, fs, fsp); err != nil {
+				return fmt.Errorf("entity \"%s\": %w", entityId, err)
+			}
+			fmt.Printf("entity \"%s\" deleted\n", entityId)
+		}
+		return nil
+	},
+}
+
+var editEntityCmd = &cobra.Command{
+	Use:     "entities ID",
+	Aliases: []string{"entity", "ent"},
+	Short:   "Edit entity. Aliases: [\"entity\", \"ent\"]",
+	Long:    "Edit entity in the editor and replace its attributes with the result",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return newUsageError("requires exactly one entity ID")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id := args[0]
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		current, err := client.GetEntity(cmd.Context(), id, entityType, fs, fsp)
+		if err != nil {
+			return fmt.Errorf("entity \"%s\": %w", id, err)
+		}
+
+		return editResource("entity", current, nil, func(data []byte) error {
+			var edited orion.Entity
+			m := manifest{Kind: "Entity", Source: "entity \"" + id + "\"", Data: data}
+			if err := m.decode(&edited); err != nil {
+				return err
+			}
+			if edited.Id != current.Id || edited.Type != current.Type {
+				return newUsageError("id and type cannot be changed")
+			}
+			if sameJSON(normalizeEntity(*current), normalizeEntity(edited)) {
+				fmt.Printf("entity \"%s\" unchanged\n", id)
+				return nil
+			}
+			if err := client.ReplaceEntityAttrs(cmd.Context(), edited, fs, fsp); err != nil {
+				return err
+			}
+			fmt.Printf("entity \"%s\" edited\n", id)
+			return nil
+		})
+	},
+}
+
+var exportEntityCmd = &cobra.Command{
+	Use:     "entities",
+	Aliases: []string{"entity", "ent"},
+	Short:   "Export entity. Aliases: [\"entity\", \"ent\"]",
+	Long:    "Export entities as manifests",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		entities, err := client.GetEntities(cmd.Context(), orion.EntityQuery{Type: entityType}, fs, fsp)
+		if err != nil {
+			return err
+		}
+
+		docs := make([]exportDoc, 0, len(entities))
+		for _, entity := range entities {
+			doc, err := newExportDoc("Entity", exportName("entity", entity.Type, entity.Id), entity, nil)
+			if err != nil {
+				return err
+			}
+			docs = append(docs, doc)
+		}
+		return writeExport(docs)
+	},
+}
+
+func entityTable(entities []*orion.Entity, wide bool) *uitable.Table {
+	table := uitable.New()
+	table.MaxColWidth = 50
+	if wide {
+		table.AddRow("ID", "Type", "Attributes", "Attribute Names")
+	} else {
+		table.AddRow("ID", "Type", "Attributes")
+	}
+	for _, entity := range entities {
+		if wide {
+			table.AddRow(entity.Id, entity.Type, len(entity.Attrs), strings.Join(sortedAttrNames(entity.Attrs), ","))
+		} else {
+			table.AddRow(entity.Id, entity.Type, len(entity.Attrs))
+		}
+	}
+	return table
+}
+
+func describeEntityTable(entities []*orion.Entity) *uitable.Table {
+	table := uitable.New()
+	table.MaxColWidth = 80
+	table.Wrap = true
+	for _, entity := range entities {
+		table.AddRow("ID:", entity.Id)
+		table.AddRow("Type:", entity.Type)
+		table.AddRow("Attributes:")
+		for _, name := range sortedAttrNames(entity.Attrs) {
+			attr := entity.Attrs[name]
+			table.AddRow("    " + name + ":")
+			table.AddRow("        Type:", attr.Type)
+			table.AddRow("        Value:", formatValue(attr.Value))
+			if len(attr.Metadata) > 0 {
+				table.AddRow("        Metadata:")
+				for _, mdName := range sortedMetadataNames(attr.Metadata) {
+					md := attr.Metadata[mdName]
+					table.AddRow("            "+mdName+":", formatValue(md.Value)+" ("+md.Type+")")
+				}
+			}
+		}
+		table.AddRow("")
+	}
+	return table
+}
+
+func sortedAttrNames(attrs map[string]orion.Attribute) []string {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedMetadataNames(metadata map[string]orion.Metadata) []string {
+	names := make([]string, 0, len(metadata))
+	for name := range metadata {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func formatValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		b, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(b)
+	}
+}
+
+func init() {
+	getCmd.AddCommand(getEntityCmd)
+	addPageFlags(getEntityCmd)
+	getEntityCmd.Flags().StringVarP(&entityType, "type", "t", "", "Entity type")
+	getEntityCmd.Flags().StringVar(&entityQuery.IdPattern, "id-pattern", "", "Regular expression matching entity IDs")
+	getEntityCmd.Flags().StringVar(&entityQuery.Q, "q", "", "Simple Query Language filter on attribute values (e.g. \"temperature>20;humidity==50..70\")")
+	getEntityCmd.Flags().StringVar(&entityQuery.MQ, "mq", "", "Simple Query Language filter on attribute metadata (e.g. \"temperature.accuracy<0.5\")")
+	getEntityCmd.Flags().StringVar(&entityQuery.Attrs, "attrs", "", "Comma separated list of attributes to retrieve")
+	getEntityCmd.Flags().StringVar(&entityQuery.Metadata, "metadata", "", "Comma separated list of metadata to retrieve")
+	getEntityCmd.Flags().StringVar(&entityQuery.OrderBy, "order-by", "", "Comma separated list of attributes to sort by, prefix with ! for descending order")
+	getEntityCmd.Flags().StringVar(&entityQuery.Georel, "georel", "", "Geographical relationship (near;maxDistance:N, coveredBy, intersects, equals, disjoint)")
+	getEntityCmd.Flags().StringVar(&entityQuery.Geometry, "geometry", "", "Reference geometry (point, line, polygon, box)")
+	getEntityCmd.Flags().StringVar(&entityQuery.Coords, "coords", "", "Reference coordinates as lat,lon pairs separated by ;")
+	getEntityCmd.Flags().StringVar(&near, "near", "", "Find entities near a lat,lon point, use with --max-distance or --min-distance")
+	getEntityCmd.Flags().Float64Var(&maxDistance, "max-distance", 0, "Maximum distance in meters from --near")
+	getEntityCmd.Flags().Float64Var(&minDistance, "min-distance", 0, "Minimum distance in meters from --near")
+	describeEntityCmd.Flags().StringVarP(&entityType, "type", "t", "", "Entity type")
+	describeCmd.AddCommand(describeEntityCmd)
+	createEntityCmd.Flags().StringVarP(&entityFile, "entityFile", "f", "", "Entity resource filename, URL or - for stdin")
+	createCmd.AddCommand(createEntityCmd)
+	deleteEntityCmd.Flags().StringVarP(&entityType, "type", "t", "", "Entity type")
+	deleteCmd.AddCommand(deleteEntityCmd)
+	editEntityCmd.Flags().StringVarP(&entityType, "type", "t", "", "Entity type")
+	editCmd.AddCommand(editEntityCmd)
+	exportEntityCmd.Flags().StringVarP(&entityType, "type", "t", "", "Entity type")
+	exportCmd.AddCommand(exportEntityCmd)
+}
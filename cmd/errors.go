@@ -0,0 +1,111 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/YujiAzama/orionctl/orion"
+)
+
+// Exit codes returned by orionctl.
+const (
+	// exitError is returned for errors not covered by a more specific code.
+	exitError = 1
+	// exitUsage is returned for invalid flags, arguments, queries or
+	// resource files, and for requests Orion rejects as bad requests.
+	exitUsage = 2
+	// exitNotFound is returned when a requested resource does not exist.
+	exitNotFound = 3
+	// exitAuth is returned when Orion or its proxy rejects the credentials.
+	exitAuth = 4
+	// exitConnection is returned when Orion cannot be reached.
+	exitConnection = 5
+	// exitDrift is returned by diff when live resources differ from the
+	// manifests.
+	exitDrift = 6
+	// exitInterrupted is returned when orionctl is interrupted by SIGINT or
+	// SIGTERM.
+	exitInterrupted = 130
+)
+
+// exitStatus ends a command with an exit code without reporting an error.
+type exitStatus struct {
+	code int
+}
+
+func (e *exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// usageError reports invalid input detected before contacting Orion.
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+func newUsageError(format string, a ...interface{}) error {
+	return &usageError{err: fmt.Errorf(format, a...)}
+}
+
+// exitCode returns the exit code documented for err.
+func exitCode(err error) int {
+	var orionErr *orion.Error
+	var queryErr *orion.QueryError
+	var usageErr *usageError
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	var urlErr *url.Error
+	var hostErr x509.HostnameError
+	var authorityErr x509.UnknownAuthorityError
+	var certErr x509.CertificateInvalidError
+	var status *exitStatus
+
+	switch {
+	case errors.As(err, &status):
+		return status.code
+	case errors.As(err, &usageErr), errors.As(err, &queryErr):
+		return exitUsage
+	case errors.As(err, &orionErr):
+		switch orionErr.StatusCode {
+		case http.StatusNotFound:
+			return exitNotFound
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return exitAuth
+		case http.StatusBadRequest:
+			return exitUsage
+		}
+	case errors.As(err, &opErr), errors.As(err, &dnsErr):
+		return exitConnection
+	case errors.As(err, &urlErr) && urlErr.Timeout():
+		return exitConnection
+	case errors.As(err, &hostErr), errors.As(err, &authorityErr), errors.As(err, &certErr):
+		return exitConnection
+	}
+	return exitError
+}
@@ -0,0 +1,230 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var exportDir string
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export Orion resources as manifests",
+	Long: `Export Orion resources as manifests
+
+The resources of the current service and service path are written as
+manifests that create or apply can read back. Fields maintained by Orion,
+such as IDs, status and notification statistics, are left out.
+
+Manifests are printed as a YAML stream, or as a JSON array with -o json.
+With --output-dir, every resource is written to its own file instead.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Help()
+			os.Exit(0)
+		}
+	},
+}
+
+// exportDoc is a resource to export, named by the file it is written to
+// with --output-dir.
+type exportDoc struct {
+	name string
+	doc  yaml.MapSlice
+}
+
+// newExportDoc returns the manifest of resource, declaring kind and
+// without the given top level fields.
+func newExportDoc(kind string, name string, resource interface{}, drop []string) (exportDoc, error) {
+	doc, err := orderedFields(resource, drop)
+	if err != nil {
+		return exportDoc{}, err
+	}
+	fields, _ := doc.(yaml.MapSlice)
+	fields = append(yaml.MapSlice{{Key: "kind", Value: kind}}, fields...)
+	return exportDoc{name: name, doc: fields}, nil
+}
+
+// exportResources returns the subscriptions or registrations of the given
+// kind, as Orion returns them, as manifests without the fields Orion
+// maintains.
+func exportResources(kind string, raw []json.RawMessage) ([]exportDoc, error) {
+	docs := make([]exportDoc, 0, len(raw))
+	for _, item := range raw {
+		var resource struct {
+			Id string `json:"id"`
+		}
+		if err := json.Unmarshal(item, &resource); err != nil {
+			return nil, err
+		}
+		doc, err := newExportDoc(kind, exportName(strings.ToLower(kind), resource.Id), item, runtimeFields(kind))
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// writeExport prints docs in the format selected by --output, or writes
+// them to --output-dir.
+func writeExport(docs []exportDoc) error {
+	format := output
+	switch format {
+	case "":
+		format = "yaml"
+	case "yaml", "json":
+	default:
+		return newUsageError("unknown output format \"%s\" for export. One of: json, yaml", output)
+	}
+
+	if exportDir == "" {
+		if format == "json" {
+			stream := make([]yaml.MapSlice, 0, len(docs))
+			for _, d := range docs {
+				stream = append(stream, d.doc)
+			}
+			b, err := encodeExport(stream, format)
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(b))
+			return nil
+		}
+		for i, d := range docs {
+			b, err := encodeExport(d.doc, format)
+			if err != nil {
+				return err
+			}
+			if i > 0 {
+				fmt.Println("---")
+			}
+			fmt.Print(string(b))
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(exportDir, 0755); err != nil {
+		return err
+	}
+	for _, d := range docs {
+		b, err := encodeExport(d.doc, format)
+		if err != nil {
+			return err
+		}
+		file := filepath.Join(exportDir, d.name+"."+format)
+		if err := ioutil.WriteFile(file, b, 0644); err != nil {
+			return err
+		}
+		fmt.Println(file)
+	}
+	return nil
+}
+
+func encodeExport(v interface{}, format string) ([]byte, error) {
+	if format == "yaml" {
+		return yaml.Marshal(v)
+	}
+	b, err := orderedJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, b, "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteString("\n")
+	return out.Bytes(), nil
+}
+
+// orderedJSON encodes v as JSON, keeping the field order of the
+// yaml.MapSlice values in it.
+func orderedJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		buf.WriteString("{")
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			key, err := json.Marshal(fmt.Sprint(item.Key))
+			if err != nil {
+				return nil, err
+			}
+			value, err := orderedJSON(item.Value)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(key)
+			buf.WriteString(":")
+			buf.Write(value)
+		}
+		buf.WriteString("}")
+	case []yaml.MapSlice:
+		items := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			items = append(items, item)
+		}
+		return orderedJSON(items)
+	case []interface{}:
+		buf.WriteString("[")
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			value, err := orderedJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(value)
+		}
+		buf.WriteString("]")
+	default:
+		return json.Marshal(v)
+	}
+	return buf.Bytes(), nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// exportName returns a file name for a resource from the given parts.
+func exportName(parts ...string) string {
+	name := ""
+	for i, part := range parts {
+		if i > 0 {
+			name += "-"
+		}
+		name += unsafeFileChars.ReplaceAllString(part, "_")
+	}
+	return name
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.PersistentFlags().StringVar(&exportDir, "output-dir", "", "Write every resource to its own file in this directory")
+}
@@ -0,0 +1,155 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/YujiAzama/orionctl/orion"
+)
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string                 `json:"type"`
+	Id         string                 `json:"id"`
+	Geometry   interface{}            `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// toFeatureCollection converts entities into a GeoJSON FeatureCollection.
+// The first attribute holding a location ("location" is preferred) becomes
+// the feature geometry and every other attribute value becomes a property.
+func toFeatureCollection(entities []*orion.Entity) featureCollection {
+	collection := featureCollection{Type: "FeatureCollection", Features: []feature{}}
+	for _, entity := range entities {
+		f := feature{
+			Type: "Feature",
+			Id:   entity.Id,
+			Properties: map[string]interface{}{
+				"id":   entity.Id,
+				"type": entity.Type,
+			},
+		}
+		geoAttr := locationAttrName(entity.Attrs)
+		for name, attr := range entity.Attrs {
+			if name == geoAttr {
+				g, err := toGeometry(attr)
+				if err == nil {
+					f.Geometry = g
+					continue
+				}
+			}
+			f.Properties[name] = attr.Value
+		}
+		collection.Features = append(collection.Features, f)
+	}
+	return collection
+}
+
+func locationAttrName(attrs map[string]orion.Attribute) string {
+	if attr, ok := attrs["location"]; ok && strings.HasPrefix(attr.Type, "geo:") {
+		return "location"
+	}
+	for _, name := range sortedAttrNames(attrs) {
+		if strings.HasPrefix(attrs[name].Type, "geo:") {
+			return name
+		}
+	}
+	return ""
+}
+
+func toGeometry(attr orion.Attribute) (interface{}, error) {
+	switch attr.Type {
+	case "geo:json":
+		return attr.Value, nil
+	case "geo:point":
+		point, err := parsePoint(attr.Value)
+		if err != nil {
+			return nil, err
+		}
+		return geometry{Type: "Point", Coordinates: point}, nil
+	case "geo:line":
+		line, err := parsePoints(attr.Value)
+		if err != nil {
+			return nil, err
+		}
+		return geometry{Type: "LineString", Coordinates: line}, nil
+	case "geo:polygon":
+		ring, err := parsePoints(attr.Value)
+		if err != nil {
+			return nil, err
+		}
+		return geometry{Type: "Polygon", Coordinates: [][][]float64{ring}}, nil
+	case "geo:box":
+		corners, err := parsePoints(attr.Value)
+		if err != nil || len(corners) != 2 {
+			return nil, fmt.Errorf("invalid geo:box")
+		}
+		sw, ne := corners[0], corners[1]
+		ring := [][]float64{sw, {ne[0], sw[1]}, ne, {sw[0], ne[1]}, sw}
+		return geometry{Type: "Polygon", Coordinates: [][][]float64{ring}}, nil
+	}
+	return nil, fmt.Errorf("unsupported location type %q", attr.Type)
+}
+
+// parsePoint converts an NGSIv2 "lat, lon" string into a GeoJSON [lon, lat]
+// position.
+func parsePoint(value interface{}) ([]float64, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid point %v", value)
+	}
+	latlon := strings.Split(s, ",")
+	if len(latlon) != 2 {
+		return nil, fmt.Errorf("invalid point %q", s)
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latlon[0]), 64)
+	if err != nil {
+		return nil, err
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(latlon[1]), 64)
+	if err != nil {
+		return nil, err
+	}
+	return []float64{lon, lat}, nil
+}
+
+func parsePoints(value interface{}) ([][]float64, error) {
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid point list %v", value)
+	}
+	points := make([][]float64, 0, len(values))
+	for _, v := range values {
+		point, err := parsePoint(v)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
@@ -0,0 +1,472 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPathNode is one element of a parsed JSONPath template: literal text,
+// a path expression, or a range block over a path expression.
+type jsonPathNode struct {
+	text    string
+	path    string
+	isRange bool
+	body    []jsonPathNode
+}
+
+// executeJSONPath evaluates a kubectl style JSONPath template such as
+// "{.notification.http.url}" or "{range .subject.entities[*]}{.id}{\"\\n\"}{end}"
+// against data, which must be the generic JSON decoding of a resource.
+func executeJSONPath(template string, data interface{}) (string, error) {
+	nodes, _, err := parseJSONPath(template, false)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := evalJSONPathNodes(&buf, nodes, data, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func parseJSONPath(template string, inRange bool) ([]jsonPathNode, string, error) {
+	var nodes []jsonPathNode
+	for template != "" {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			nodes = append(nodes, jsonPathNode{text: template})
+			break
+		}
+		if start > 0 {
+			nodes = append(nodes, jsonPathNode{text: template[:start]})
+		}
+		end := matchingBrace(template, start)
+		if end < 0 {
+			return nil, "", fmt.Errorf("unclosed { in jsonpath template")
+		}
+		expr := strings.TrimSpace(template[start+1 : end])
+		template = template[end+1:]
+		switch {
+		case expr == "end":
+			if !inRange {
+				return nil, "", fmt.Errorf("unexpected {end} in jsonpath template")
+			}
+			return nodes, template, nil
+		case strings.HasPrefix(expr, "range "):
+			body, rest, err := parseJSONPath(template, true)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, jsonPathNode{path: strings.TrimSpace(expr[len("range "):]), isRange: true, body: body})
+			template = rest
+		case strings.HasPrefix(expr, "\""):
+			text, err := strconv.Unquote(expr)
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid string literal %s in jsonpath template", expr)
+			}
+			nodes = append(nodes, jsonPathNode{text: text})
+		default:
+			nodes = append(nodes, jsonPathNode{path: expr})
+		}
+	}
+	if inRange {
+		return nil, "", fmt.Errorf("{range} without {end} in jsonpath template")
+	}
+	return nodes, "", nil
+}
+
+func matchingBrace(s string, start int) int {
+	depth := 0
+	var quote byte
+	for i := start; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == '\\' {
+				i++
+			} else if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == '{':
+			depth++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func evalJSONPathNodes(buf *bytes.Buffer, nodes []jsonPathNode, root, current interface{}) error {
+	for _, node := range nodes {
+		if node.path == "" {
+			buf.WriteString(node.text)
+			continue
+		}
+		values, err := evalJSONPath(node.path, root, current)
+		if err != nil {
+			return err
+		}
+		if node.isRange {
+			for _, value := range values {
+				if err := evalJSONPathNodes(buf, node.body, root, value); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		for i, value := range values {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteString(formatValue(value))
+		}
+	}
+	return nil
+}
+
+// evalJSONPath returns the values selected by a single path expression.
+func evalJSONPath(path string, root, current interface{}) ([]interface{}, error) {
+	values := []interface{}{current}
+	switch {
+	case strings.HasPrefix(path, "$"):
+		values = []interface{}{root}
+		path = path[1:]
+	case strings.HasPrefix(path, "@"):
+		path = path[1:]
+	}
+
+	for path != "" {
+		switch {
+		case strings.HasPrefix(path, ".."):
+			name, rest := splitPathField(path[2:])
+			var found []interface{}
+			for _, value := range values {
+				found = append(found, descend(value, name)...)
+			}
+			values, path = found, rest
+		case path[0] == '.':
+			name, rest := splitPathField(path[1:])
+			if name == "" {
+				path = rest
+				continue
+			}
+			var found []interface{}
+			for _, value := range values {
+				found = append(found, field(value, name)...)
+			}
+			values, path = found, rest
+		case path[0] == '[':
+			end := matchingBracket(path)
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in jsonpath expression")
+			}
+			var found []interface{}
+			for _, value := range values {
+				selected, err := subscript(strings.TrimSpace(path[1:end]), root, value)
+				if err != nil {
+					return nil, err
+				}
+				found = append(found, selected...)
+			}
+			values, path = found, path[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid jsonpath expression at %q", path)
+		}
+	}
+	return values, nil
+}
+
+func splitPathField(path string) (string, string) {
+	end := strings.IndexAny(path, ".[")
+	if end < 0 {
+		return path, ""
+	}
+	return path[:end], path[end:]
+}
+
+func matchingBracket(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == '[':
+			depth++
+		case s[i] == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func field(value interface{}, name string) []interface{} {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if name == "*" {
+		var values []interface{}
+		for _, key := range sortedKeys(m) {
+			values = append(values, m[key])
+		}
+		return values
+	}
+	if v, ok := m[name]; ok {
+		return []interface{}{v}
+	}
+	return nil
+}
+
+func descend(value interface{}, name string) []interface{} {
+	var found []interface{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		found = append(found, field(v, name)...)
+		for _, key := range sortedKeys(v) {
+			found = append(found, descend(v[key], name)...)
+		}
+	case []interface{}:
+		for _, item := range v {
+			found = append(found, descend(item, name)...)
+		}
+	}
+	return found
+}
+
+func subscript(expr string, root, value interface{}) ([]interface{}, error) {
+	if parts := splitUnion(expr); len(parts) > 1 {
+		var values []interface{}
+		for _, part := range parts {
+			selected, err := subscript(strings.TrimSpace(part), root, value)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, selected...)
+		}
+		return values, nil
+	}
+	if strings.HasPrefix(expr, "'") || strings.HasPrefix(expr, "\"") {
+		name := strings.Trim(expr, "'\"")
+		return field(value, name), nil
+	}
+	if strings.HasPrefix(expr, "?(") && strings.HasSuffix(expr, ")") {
+		return filter(expr[2:len(expr)-1], root, value)
+	}
+
+	if expr == "*" {
+		if m, ok := value.(map[string]interface{}); ok {
+			return field(m, "*"), nil
+		}
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	if expr == "*" {
+		return list, nil
+	}
+	if strings.Contains(expr, ":") {
+		bounds := strings.SplitN(expr, ":", 2)
+		start, end := 0, len(list)
+		var err error
+		if bounds[0] != "" {
+			if start, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid slice [%s] in jsonpath expression", expr)
+			}
+		}
+		if bounds[1] != "" {
+			if end, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, fmt.Errorf("invalid slice [%s] in jsonpath expression", expr)
+			}
+		}
+		start, end = clampIndex(start, len(list)), clampIndex(end, len(list))
+		if start >= end {
+			return nil, nil
+		}
+		return list[start:end], nil
+	}
+	index, err := strconv.Atoi(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subscript [%s] in jsonpath expression", expr)
+	}
+	if index < 0 {
+		index += len(list)
+	}
+	if index < 0 || index >= len(list) {
+		return nil, nil
+	}
+	return []interface{}{list[index]}, nil
+}
+
+// splitUnion splits a union subscript such as "0,2" or "'id','type'" on
+// the commas outside quotes and filters.
+func splitUnion(expr string) []string {
+	if strings.HasPrefix(expr, "?(") {
+		return []string{expr}
+	}
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(expr); i++ {
+		switch {
+		case quote != 0:
+			if expr[i] == quote {
+				quote = 0
+			}
+		case expr[i] == '"' || expr[i] == '\'':
+			quote = expr[i]
+		case expr[i] == ',':
+			parts = append(parts, expr[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, expr[start:])
+}
+
+func clampIndex(i int, n int) int {
+	if i < 0 {
+		i += n
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > n {
+		return n
+	}
+	return i
+}
+
+var filterOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func filter(expr string, root, value interface{}) ([]interface{}, error) {
+	var items []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		items = v
+	case map[string]interface{}:
+		items = field(v, "*")
+	default:
+		return nil, nil
+	}
+
+	left, op, right := expr, "", ""
+	for _, candidate := range filterOperators {
+		if i := strings.Index(expr, candidate); i >= 0 {
+			left, op, right = strings.TrimSpace(expr[:i]), candidate, strings.TrimSpace(expr[i+len(candidate):])
+			break
+		}
+	}
+
+	var selected []interface{}
+	for _, item := range items {
+		values, err := evalJSONPath(left, root, item)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			continue
+		}
+		if op == "" || compare(values[0], op, right) {
+			selected = append(selected, item)
+		}
+	}
+	return selected, nil
+}
+
+func compare(value interface{}, op string, literal string) bool {
+	if s, err := strconv.Unquote(strings.Replace(literal, "'", "\"", -1)); err == nil {
+		actual, ok := value.(string)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "==":
+			return actual == s
+		case "!=":
+			return actual != s
+		case "<":
+			return actual < s
+		case ">":
+			return actual > s
+		case "<=":
+			return actual <= s
+		case ">=":
+			return actual >= s
+		}
+		return false
+	}
+	n, err := strconv.ParseFloat(literal, 64)
+	actual, ok := value.(float64)
+	if err != nil || !ok {
+		return op == "!=" && fmt.Sprint(value) != literal || op == "==" && fmt.Sprint(value) == literal
+	}
+	switch op {
+	case "==":
+		return actual == n
+	case "!=":
+		return actual != n
+	case "<":
+		return actual < n
+	case ">":
+		return actual > n
+	case "<=":
+		return actual <= n
+	case ">=":
+		return actual >= n
+	}
+	return false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// toGeneric returns the generic JSON decoding of v, so that templates see
+// the same field names as the json output.
+func toGeneric(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
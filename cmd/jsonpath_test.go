@@ -0,0 +1,170 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const jsonPathTestData = `{
+  "id": "s1",
+  "description": "Room temperature",
+  "subject": {
+    "entities": [
+      {"id": "Room1", "type": "Room"},
+      {"id": "Room2", "type": "Room"},
+      {"idPattern": "Car.*", "type": "Car"}
+    ],
+    "condition": {"attrs": ["temperature", "humidity"]}
+  },
+  "notification": {
+    "http": {"url": "http://localhost:1028/accumulate"},
+    "attrs": [],
+    "timesSent": 12,
+    "lastSuccess": "2020-09-01T00:00:00.000Z"
+  },
+  "throttling": 5,
+  "rooms": [
+    {"name": "a", "temperature": 21.5, "open": true},
+    {"name": "b", "temperature": 18, "open": false},
+    {"name": "c", "temperature": 25}
+  ],
+  "tags": {"floor": "1", "wing": "east"}
+}`
+
+func jsonPathData(t *testing.T) interface{} {
+	var data interface{}
+	if err := json.Unmarshal([]byte(jsonPathTestData), &data); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestExecuteJSONPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		// Fields
+		{"field", "{.id}", "s1"},
+		{"nested field", "{.notification.http.url}", "http://localhost:1028/accumulate"},
+		{"root", "{$.id}", "s1"},
+		{"current", "{@.id}", "s1"},
+		{"number", "{.throttling}", "5"},
+		{"object", "{.subject.condition}", `{"attrs":["temperature","humidity"]}`},
+		{"empty array", "{.notification.attrs}", "[]"},
+		{"missing field", "{.expires}", ""},
+		{"wildcard field", "{.tags.*}", "1 east"},
+		{"quoted field", "{.tags['wing']}", "east"},
+		{"double quoted field", `{.tags["floor"]}`, "1"},
+
+		// Indexes and slices
+		{"index", "{.subject.entities[0].id}", "Room1"},
+		{"negative index", "{.subject.entities[-1].type}", "Car"},
+		{"index out of range", "{.subject.entities[5].id}", ""},
+		{"wildcard index", "{.subject.entities[*].type}", "Room Room Car"},
+		{"slice", "{.subject.entities[0:2].id}", "Room1 Room2"},
+		{"open slice start", "{.subject.entities[:1].id}", "Room1"},
+		{"open slice end", "{.subject.entities[1:].type}", "Room Car"},
+		{"negative slice", "{.subject.entities[-2:].type}", "Room Car"},
+		{"empty slice", "{.subject.entities[2:1].id}", ""},
+		{"subscript on object", "{.tags[0]}", ""},
+
+		// Unions
+		{"index union", "{.rooms[0,2].name}", "a c"},
+		{"field union", "{.subject.entities[0]['id','type']}", "Room1 Room"},
+
+		// Recursive descent
+		{"descent", "{..url}", "http://localhost:1028/accumulate"},
+		{"descent into arrays", "{..idPattern}", "Car.*"},
+
+		// Filters
+		{"filter string", "{.subject.entities[?(@.type=='Car')].idPattern}", "Car.*"},
+		{"filter double quoted string", `{.subject.entities[?(@.type!="Room")].idPattern}`, "Car.*"},
+		{"filter number", "{.rooms[?(@.temperature>20)].name}", "a c"},
+		{"filter number equal", "{.rooms[?(@.temperature==18)].name}", "b"},
+		{"filter less or equal", "{.rooms[?(@.temperature<=21.5)].name}", "a b"},
+		{"filter greater or equal", "{.rooms[?(@.temperature>=21.5)].name}", "a c"},
+		{"filter less", "{.rooms[?(@.temperature<20)].name}", "b"},
+		{"filter boolean", "{.rooms[?(@.open==true)].name}", "a"},
+		{"filter existence", "{.rooms[?(@.open)].name}", "a b"},
+		{"filter string order", "{.rooms[?(@.name>'a')].name}", "b c"},
+		{"filter on object values", "{.tags[?(@=='east')]}", "east"},
+
+		// Text, literals and ranges
+		{"text", "id={.id}", "id=s1"},
+		{"string literal", `{.id}{"\t"}{.throttling}{"\n"}`, "s1\t5\n"},
+		{"range", `{range .subject.entities[*]}{.type}/{.id}{"\n"}{end}`, "Room/Room1\nRoom/Room2\nCar/\n"},
+		{"nested range", `{range .rooms[0:2]}{.name}:{range .name}{.}{end};{end}`, "a:a;b:b;"},
+		{"range with root", `{range .rooms[*]}{$.id}-{.name} {end}`, "s1-a s1-b s1-c "},
+		{"no expression", "plain", "plain"},
+	}
+	data := jsonPathData(t)
+	for _, test := range tests {
+		got, err := executeJSONPath(test.template, data)
+		if err != nil {
+			t.Errorf("%s: executeJSONPath(%q) returned error %v", test.name, test.template, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: executeJSONPath(%q) = %q, want %q", test.name, test.template, got, test.want)
+		}
+	}
+}
+
+func TestExecuteJSONPathErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{"unclosed brace", "{.id"},
+		{"unclosed bracket", "{.subject.entities[0}"},
+		{"unexpected end", "{.id}{end}"},
+		{"range without end", "{range .rooms[*]}{.name}"},
+		{"invalid string literal", `{"\q"}`},
+		{"invalid index", "{.rooms[x]}"},
+		{"invalid slice start", "{.rooms[a:2]}"},
+		{"invalid slice end", "{.rooms[0:b]}"},
+		{"invalid expression", "{id}"},
+		{"invalid union member", "{.rooms[0,x]}"},
+	}
+	data := jsonPathData(t)
+	for _, test := range tests {
+		if got, err := executeJSONPath(test.template, data); err == nil {
+			t.Errorf("%s: executeJSONPath(%q) = %q, want an error", test.name, test.template, got)
+		}
+	}
+}
+
+func TestToGeneric(t *testing.T) {
+	v := struct {
+		ID    string `json:"id"`
+		Count int    `json:"count,omitempty"`
+	}{ID: "s1"}
+	generic, err := toGeneric(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := executeJSONPath("{.id}{.count}", generic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "s1" {
+		t.Errorf("got %q, want %q", got, "s1")
+	}
+}
@@ -0,0 +1,262 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Resource kinds accepted in the kind field of a manifest, in the order
+// they are created so that registrations and subscriptions can refer to
+// entities declared alongside them.
+var manifestKinds = []string{"Entity", "Registration", "Subscription"}
+
+// manifest is a single resource document read from a resource file.
+type manifest struct {
+	Kind   string
+	Source string
+	Data   []byte
+}
+
+// decode decodes the manifest, without its kind field, into v.
+func (m manifest) decode(v interface{}) error {
+	if err := json.Unmarshal(m.Data, v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return newUsageError("%s: field %s must be %s, not %s", m.Source, typeErr.Field, typeErr.Type, typeErr.Value)
+		}
+		return &usageError{err: fmt.Errorf("%s: %w", m.Source, err)}
+	}
+	return nil
+}
+
+// readManifests reads every resource document at location, which is a
+// file, a directory of .json, .yaml and .yml files, "-" for stdin, or an
+// http(s) URL. YAML files may hold several documents and JSON files may
+// hold an array of resources. Directories are only descended into when
+// recursive is set.
+func readManifests(location string, recursive bool) ([]manifest, error) {
+	if location == "" {
+		return nil, newUsageError("a resource file is required, use -f FILENAME")
+	}
+
+	var files []string
+	if info, err := os.Stat(location); err == nil && info.IsDir() {
+		err := filepath.Walk(location, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if path != location && !recursive {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".json", ".yaml", ".yml":
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, &usageError{err: err}
+		}
+		if len(files) == 0 {
+			return nil, newUsageError("%s: no .json, .yaml or .yml files found", location)
+		}
+	} else {
+		files = []string{location}
+	}
+
+	var manifests []manifest
+	for _, file := range files {
+		data, err := readLocation(file)
+		if err != nil {
+			return nil, &usageError{err: err}
+		}
+		docs, err := parseManifests(file, data)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, docs...)
+	}
+	return manifests, nil
+}
+
+func parseManifests(source string, data []byte) ([]manifest, error) {
+	var docs []interface{}
+	if isJSON(source, data) {
+		if err := checkJSON(data); err != nil {
+			return nil, &usageError{err: fmt.Errorf("%s: %w", source, err)}
+		}
+		var doc interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, &usageError{err: fmt.Errorf("%s: %w", source, err)}
+		}
+		if list, ok := doc.([]interface{}); ok {
+			docs = list
+		} else {
+			docs = []interface{}{doc}
+		}
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var doc interface{}
+			err := dec.Decode(&doc)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, &usageError{err: fmt.Errorf("%s: %s", source, strings.TrimPrefix(err.Error(), "yaml: "))}
+			}
+			if doc != nil {
+				docs = append(docs, convertYAML(doc))
+			}
+		}
+	}
+
+	manifests := make([]manifest, 0, len(docs))
+	for i, doc := range docs {
+		name := source
+		if len(docs) > 1 {
+			name = fmt.Sprintf("%s#%d", source, i+1)
+		}
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, newUsageError("%s: resource must be an object", name)
+		}
+		kind := ""
+		if k, ok := obj["kind"]; ok {
+			kind = manifestKind(fmt.Sprint(k))
+			if kind == "" {
+				return nil, newUsageError("%s: unknown kind \"%v\", must be one of %s", name, k, strings.Join(manifestKinds, ", "))
+			}
+			delete(obj, "kind")
+		}
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest{Kind: kind, Source: name, Data: b})
+	}
+	return manifests, nil
+}
+
+func manifestKind(kind string) string {
+	for _, k := range manifestKinds {
+		if strings.EqualFold(k, kind) {
+			return k
+		}
+	}
+	return ""
+}
+
+// requireKind checks that every manifest is of the given kind, filling in
+// the kind of manifests that do not declare one.
+func requireKind(manifests []manifest, kind string) error {
+	for i := range manifests {
+		switch manifests[i].Kind {
+		case "":
+			manifests[i].Kind = kind
+		case kind:
+		default:
+			return newUsageError("%s: expected kind %s, got %s", manifests[i].Source, kind, manifests[i].Kind)
+		}
+	}
+	return nil
+}
+
+// sortManifests orders manifests by kind in creation order, keeping the
+// order of the files otherwise.
+func sortManifests(manifests []manifest) {
+	rank := func(kind string) int {
+		for i, k := range manifestKinds {
+			if k == kind {
+				return i
+			}
+		}
+		return len(manifestKinds)
+	}
+	sort.SliceStable(manifests, func(i, j int) bool {
+		return rank(manifests[i].Kind) < rank(manifests[j].Kind)
+	})
+}
+
+func readLocation(location string) ([]byte, error) {
+	switch {
+	case location == "-":
+		return ioutil.ReadAll(os.Stdin)
+	case strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://"):
+		resp, err := http.Get(location)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s: %s", location, resp.Status)
+		}
+		return ioutil.ReadAll(resp.Body)
+	default:
+		return ioutil.ReadFile(location)
+	}
+}
+
+// isJSON decides whether a manifest is JSON by its extension, falling back
+// to its first non blank character.
+func isJSON(location string, data []byte) bool {
+	switch strings.ToLower(filepath.Ext(strings.SplitN(location, "?", 2)[0])) {
+	case ".json":
+		return true
+	case ".yaml", ".yml":
+		return false
+	}
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
+
+// checkJSON reports syntax errors with their line and column.
+func checkJSON(data []byte) error {
+	var v interface{}
+	err := json.Unmarshal(data, &v)
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		line, column := position(data, syntaxErr.Offset)
+		return fmt.Errorf("line %d, column %d: %v", line, column, err)
+	}
+	return err
+}
+
+func position(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
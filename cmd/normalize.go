@@ -0,0 +1,333 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/YujiAzama/orionctl/orion"
+)
+
+// subscriptionRuntimeFields and registrationRuntimeFields are the fields
+// maintained by Orion, as dotted paths.
+var subscriptionRuntimeFields = []string{
+	"id", "status",
+	"notification.timesSent", "notification.lastNotification",
+	"notification.lastFailure", "notification.lastFailureReason",
+	"notification.lastSuccess", "notification.lastSuccessCode",
+	"notification.failsCounter",
+}
+var registrationRuntimeFields = []string{"id", "status", "forwardingInformation"}
+
+func runtimeFields(kind string) []string {
+	if kind == "Registration" {
+		return registrationRuntimeFields
+	}
+	return subscriptionRuntimeFields
+}
+
+// decodeResource decodes a subscription or registration as Orion returns
+// it, keeping every field.
+func decodeResource(raw json.RawMessage) (map[string]interface{}, error) {
+	var r map[string]interface{}
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// decodeResources decodes raw subscriptions or registrations into v, a
+// pointer to a slice of the orionclient types the tables are built from.
+func decodeResources(raw []json.RawMessage, v interface{}) error {
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// stripRuntime removes the fields maintained by Orion from the
+// subscription or registration r, keeping an inactive status so that
+// paused resources stay paused when they are created again.
+func stripRuntime(kind string, r map[string]interface{}) map[string]interface{} {
+	inactive := r["status"] == "inactive"
+	for _, field := range runtimeFields(kind) {
+		deleteField(r, field)
+	}
+	if inactive {
+		r["status"] = "inactive"
+	}
+	return r
+}
+
+// normalizeResource returns a copy of the subscription or registration r
+// without the fields maintained by Orion, with Orion's defaults filled in
+// and without empty values, so that a manifest and the live resource it
+// describes compare equal.
+func normalizeResource(kind string, r map[string]interface{}) map[string]interface{} {
+	n := copyValue(r).(map[string]interface{})
+	for _, field := range runtimeFields(kind) {
+		deleteField(n, field)
+	}
+	switch kind {
+	case "Subscription":
+		if notification, ok := n["notification"].(map[string]interface{}); ok && notification["attrsFormat"] == nil {
+			notification["attrsFormat"] = "normalized"
+		}
+	case "Registration":
+		if provider, ok := n["provider"].(map[string]interface{}); ok && provider["supportedForwardingMode"] == nil {
+			provider["supportedForwardingMode"] = "all"
+		}
+	}
+	if expires, ok := n["expires"].(string); ok {
+		n["expires"] = normalizeTime(expires)
+	}
+	return dropZero(n).(map[string]interface{})
+}
+
+// normalizeResourcePair normalizes a subscription or registration manifest
+// and the live resource it matches for comparison. Status is only compared
+// when the manifest declares it.
+func normalizeResourcePair(kind string, desired, live map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	d, l := normalizeResource(kind, desired), normalizeResource(kind, live)
+	if status, ok := desired["status"].(string); ok && status != "" {
+		d["status"], l["status"] = status, live["status"]
+	}
+	return d, l
+}
+
+// removedFields returns the top level fields of the live subscription or
+// registration that the manifest desired leaves out, ignoring the fields
+// Orion maintains.
+func removedFields(kind string, desired, live map[string]interface{}) []string {
+	d, l := normalizeResourcePair(kind, desired, live)
+	var removed []string
+	for name := range l {
+		if _, ok := d[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+// dropZero removes the object fields holding null, false, 0, an empty
+// string, list or object from v at any depth. Orion leaves most of those
+// out of the resources it returns.
+func dropZero(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			value = dropZero(value)
+			if isZero(value) {
+				delete(v, key)
+			} else {
+				v[key] = value
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = dropZero(value)
+		}
+	}
+	return v
+}
+
+func isZero(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// copyValue returns a deep copy of a value decoded from JSON.
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			c[key] = copyValue(value)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, value := range v {
+			c[i] = copyValue(value)
+		}
+		return c
+	}
+	return v
+}
+
+// fieldAt returns the value at the dotted path of r, or nil when there is
+// none.
+func fieldAt(r map[string]interface{}, path string) interface{} {
+	names := strings.Split(path, ".")
+	for _, name := range names[:len(names)-1] {
+		next, ok := r[name].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		r = next
+	}
+	return r[names[len(names)-1]]
+}
+
+// stringField returns the string at the dotted path of r, or "" when
+// there is none.
+func stringField(r map[string]interface{}, path string) string {
+	s, _ := fieldAt(r, path).(string)
+	return s
+}
+
+// setField sets the value at the dotted path of r, creating the objects
+// on the way.
+func setField(r map[string]interface{}, path string, value interface{}) {
+	names := strings.Split(path, ".")
+	for _, name := range names[:len(names)-1] {
+		next, ok := r[name].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			r[name] = next
+		}
+		r = next
+	}
+	r[names[len(names)-1]] = value
+}
+
+// deleteField removes the value at the dotted path of r.
+func deleteField(r map[string]interface{}, path string) {
+	names := strings.Split(path, ".")
+	for _, name := range names[:len(names)-1] {
+		next, ok := r[name].(map[string]interface{})
+		if !ok {
+			return
+		}
+		r = next
+	}
+	delete(r, names[len(names)-1])
+}
+
+// normalizeEntity returns a copy of entity with the attribute types Orion
+// infers for untyped attributes and canonical DateTime values.
+func normalizeEntity(entity orion.Entity) orion.Entity {
+	e := orion.Entity{Id: entity.Id, Type: entity.Type, Attrs: map[string]orion.Attribute{}}
+	for name, attr := range entity.Attrs {
+		if attr.Type == "" {
+			attr.Type = inferAttrType(attr.Value)
+		}
+		if s, ok := attr.Value.(string); ok && attr.Type == "DateTime" {
+			attr.Value = normalizeTime(s)
+		}
+		if len(attr.Metadata) == 0 {
+			attr.Metadata = nil
+		}
+		e.Attrs[name] = attr
+	}
+	return e
+}
+
+func inferAttrType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "None"
+	case string:
+		return "Text"
+	case float64:
+		return "Number"
+	case bool:
+		return "Boolean"
+	default:
+		return "StructuredValue"
+	}
+}
+
+// orionTimeFormat is the layout of the timestamps returned by Orion.
+const orionTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// normalizeTime formats an ISO8601 timestamp the way Orion returns it.
+func normalizeTime(value string) string {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return value
+	}
+	return t.UTC().Format(orionTimeFormat)
+}
+
+// subscriptionKey identifies a subscription across brokers and redeploys.
+// Subscription IDs are generated by Orion, so subscriptions are matched by
+// their description.
+func subscriptionKey(subscription map[string]interface{}) string {
+	return stringField(subscription, "description")
+}
+
+// notificationURLFields are the fields holding the URL a subscription
+// notifies, one for each kind of notification.
+var notificationURLFields = []string{"notification.http.url", "notification.httpCustom.url", "notification.mqtt.url", "notification.mqttCustom.url"}
+
+// notificationURL returns the URL the subscription notifies, whatever the
+// kind of notification.
+func notificationURL(subscription map[string]interface{}) string {
+	for _, path := range notificationURLFields {
+		if url := stringField(subscription, path); url != "" {
+			return url
+		}
+	}
+	return ""
+}
+
+// registrationKey identifies a registration by its description or, when
+// it has none, by its provider and the data it provides.
+func registrationKey(registration map[string]interface{}) string {
+	if description := stringField(registration, "description"); description != "" {
+		return description
+	}
+	var entities []string
+	list, _ := fieldAt(registration, "dataProvided.entities").([]interface{})
+	for _, item := range list {
+		if entity, ok := item.(map[string]interface{}); ok {
+			entities = append(entities, stringField(entity, "id")+stringField(entity, "idPattern")+"/"+stringField(entity, "type"))
+		}
+	}
+	sort.Strings(entities)
+	var attrs []string
+	list, _ = fieldAt(registration, "dataProvided.attrs").([]interface{})
+	for _, attr := range list {
+		attrs = append(attrs, fmt.Sprint(attr))
+	}
+	sort.Strings(attrs)
+	return fmt.Sprintf("%s [%s] [%s]", stringField(registration, "provider.http.url"), strings.Join(entities, ","), strings.Join(attrs, ","))
+}
+
+// entityKey identifies an entity by its ID and type.
+func entityKey(entity *orion.Entity) string {
+	return entity.Id + "/" + entity.Type
+}
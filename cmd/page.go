@@ -0,0 +1,52 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var limit int
+var offset int
+var all bool
+
+func addPageFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&limit, "limit", 0, "Maximum number of results to return (default: all)")
+	cmd.Flags().IntVar(&offset, "offset", 0, "Number of results to skip")
+	cmd.Flags().BoolVar(&all, "all", false, "Retrieve every page of results (default when --limit is not set)")
+}
+
+// pageLimit returns the number of results requested by the page flags,
+// where 0 means every result.
+func pageLimit() (int, error) {
+	if limit < 0 || offset < 0 {
+		return 0, newUsageError("--limit and --offset must not be negative")
+	}
+	if all && limit > 0 {
+		return 0, newUsageError("--all cannot be combined with --limit")
+	}
+	return limit, nil
+}
+
+func printTotal(shown int, total int) {
+	if shown == 0 || (offset == 0 && shown == total) {
+		fmt.Printf("Total: %d\n", total)
+		return
+	}
+	fmt.Printf("Showing %d-%d of %d\n", offset+1, offset+shown, total)
+}
@@ -0,0 +1,153 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/gosuri/uitable"
+)
+
+var output string
+var templateFile string
+
+// printResources prints resources in the format selected by --output.
+// items is a slice of resources; when the user asked for exactly one
+// resource by ID it is printed as a single object in json and yaml. ids
+// are printed by the name format, and table builds the default and wide
+// table formats.
+func printResources(items interface{}, single bool, ids []string, table func(wide bool) *uitable.Table) error {
+	var data = items
+	if single {
+		if v := reflect.ValueOf(items); v.Kind() == reflect.Slice && v.Len() == 1 {
+			data = v.Index(0).Interface()
+		}
+	}
+
+	if format, _ := splitTemplateOutput(); format != "" {
+		return printTemplate(items)
+	}
+
+	switch output {
+	case "":
+		fmt.Println(table(false))
+	case "wide":
+		fmt.Println(table(true))
+	case "name":
+		for _, id := range ids {
+			fmt.Println(id)
+		}
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(data)
+	case "yaml":
+		b, err := toYAML(data)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(b))
+	default:
+		return newUsageError("unknown output format \"%s\". One of: json, yaml, wide, name, jsonpath=..., go-template=...", output)
+	}
+	return nil
+}
+
+// isTableOutput reports whether --output selects a human readable table.
+func isTableOutput() bool {
+	return output == "" || output == "wide"
+}
+
+// splitTemplateOutput splits a template output format such as
+// "jsonpath={.id}" into the format name and the inline template. The format
+// is empty when --output does not select a template.
+func splitTemplateOutput() (string, string) {
+	format := output
+	template := ""
+	if i := strings.IndexByte(output, '='); i >= 0 {
+		format, template = output[:i], output[i+1:]
+	}
+	if format != "jsonpath" && format != "go-template" {
+		return "", ""
+	}
+	return format, template
+}
+
+// printTemplate executes the jsonpath or go-template output template once
+// for every resource in items.
+func printTemplate(items interface{}) error {
+	format, tmpl := splitTemplateOutput()
+	if templateFile != "" {
+		if tmpl != "" {
+			return newUsageError("--template-file cannot be combined with an inline %s template", format)
+		}
+		b, err := ioutil.ReadFile(templateFile)
+		if err != nil {
+			return err
+		}
+		tmpl = string(b)
+	}
+	if tmpl == "" {
+		return newUsageError("%s output requires a template, e.g. -o %s=TEMPLATE or --template-file", format, format)
+	}
+
+	var goTemplate *template.Template
+	if format == "go-template" {
+		var err error
+		if goTemplate, err = template.New("output").Parse(tmpl); err != nil {
+			return err
+		}
+	}
+
+	var objects []interface{}
+	if v := reflect.ValueOf(items); v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			objects = append(objects, v.Index(i).Interface())
+		}
+	} else {
+		objects = append(objects, items)
+	}
+
+	for _, object := range objects {
+		data, err := toGeneric(object)
+		if err != nil {
+			return err
+		}
+		var result string
+		if goTemplate != nil {
+			var buf bytes.Buffer
+			if err := goTemplate.Execute(&buf, data); err != nil {
+				return err
+			}
+			result = buf.String()
+		} else if result, err = executeJSONPath(tmpl, data); err != nil {
+			return err
+		}
+		if !strings.HasSuffix(result, "\n") {
+			result += "\n"
+		}
+		fmt.Print(result)
+	}
+	return nil
+}
@@ -16,190 +16,322 @@ limitations under the License.
 package cmd
 
 import (
-	"context"
-	"errors"
+	"encoding/json"
 	"fmt"
-	"os"
+	"strings"
 
+	"github.com/YujiAzama/orionclient-go/orionclient"
+	"github.com/YujiAzama/orionctl/orion"
 	"github.com/gosuri/uitable"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
-	"github.com/YujiAzama/orionclient-go/orionclient"
 )
 
 var registrationFile string
-var registration orionclient.Registration
 
 var getRegistrationCmd = &cobra.Command{
-	Use:   "registrations",
+	Use:     "registrations",
 	Aliases: []string{"registration", "regist"},
-	Short: "Get registration. Aliases: [\"registration\", \"regist\"]",
-	Long:  "Get registration",
-	Run: func(cmd *cobra.Command, args []string) {
-		oc := orionclient.ClientConfig{Host: config.Host, Port: config.Port, TLS: config.TLS, Token: config.Token}
-		client, err := orionclient.NewClient(oc)
+	Short:   "Get registration. Aliases: [\"registration\", \"regist\"]",
+	Long:    "Get registration",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
 		if err != nil {
-			panic(err)
-		}
-
-                var registrations = []*orionclient.Registration{}
-                if len(args) > 0 {
-                        for _, id := range args {
-                                registration, err := client.GetRegistration(context.Background(), id, fs, fsp)
-                                if err != nil {
-                                        panic(err)
-                                }
-                                registrations = append(registrations, registration)
-                        }
-                } else {
-                        allRegistrations, err := client.GetRegistrations(context.Background(), fs, fsp)
-                        if err != nil {
-                                panic(err)
-                        }
-                        registrations = allRegistrations
-                }
-
-		table := uitable.New()
-		table.MaxColWidth = 50
-		table.AddRow("ID", "Provider URL", "Status")
+			return err
+		}
+		maxResults, err := pageLimit()
+		if err != nil {
+			return err
+		}
+
+		var raw = []json.RawMessage{}
+		total := 0
+		if len(args) > 0 {
+			for _, id := range args {
+				registration, err := client.GetRawRegistration(cmd.Context(), id, fs, fsp)
+				if err != nil {
+					return fmt.Errorf("registration \"%s\": %w", id, err)
+				}
+				raw = append(raw, registration)
+			}
+		} else {
+			total, err = orion.Paginate(maxResults, offset, func(page orion.Page) (int, int, error) {
+				items, count, err := client.GetRawRegistrationsPage(cmd.Context(), page, fs, fsp)
+				raw = append(raw, items...)
+				return len(items), count, err
+			})
+			if err != nil {
+				return err
+			}
+		}
+		var registrations []*orionclient.Registration
+		if err := decodeResources(raw, &registrations); err != nil {
+			return err
+		}
+
+		ids := make([]string, 0, len(registrations))
 		for _, registration := range registrations {
-			table.AddRow(registration.Id, registration.Provider.HTTP.URL, registration.Status)
+			ids = append(ids, registration.Id)
+		}
+		err = printResources(raw, len(args) == 1, ids, func(wide bool) *uitable.Table {
+			return registrationTable(registrations, wide)
+		})
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 && isTableOutput() {
+			printTotal(len(registrations), total)
 		}
-		fmt.Println(table)
+		return nil
 	},
 }
 
 var describeRegistrationCmd = &cobra.Command{
-	Use:   "registrations",
+	Use:     "registrations",
 	Aliases: []string{"registration", "regist"},
-	Short: "Describe registration. Aliases: [\"registration\", \"regist\"]",
-	Long:  "Describe registration",
-	Run: func(cmd *cobra.Command, args []string) {
-		oc := orionclient.ClientConfig{Host: config.Host, Port: config.Port, TLS: config.TLS, Token: config.Token}
-		client, err := orionclient.NewClient(oc)
+	Short:   "Describe registration. Aliases: [\"registration\", \"regist\"]",
+	Long:    "Describe registration",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
 		if err != nil {
-			panic(err)
-		}
-
-                var registrations = []*orionclient.Registration{}
-                if len(args) > 0 {
-                        for _, id := range args {
-                                registration, err := client.GetRegistration(context.Background(), id, fs, fsp)
-                                if err != nil {
-                                        panic(err)
-                                }
-                                registrations = append(registrations, registration)
-                        }
-                } else {
-                        allRegistrations, err := client.GetRegistrations(context.Background(), fs, fsp)
-                        if err != nil {
-                                panic(err)
-                        }
-                        registrations = allRegistrations
-                }
-
-		table := uitable.New()
-		table.MaxColWidth = 80
-		table.Wrap = true
-		for _, registration := range registrations {
-			table.AddRow("ID:", registration.Id)
-			table.AddRow("DataProvided:")
-			for i, entity := range registration.DataProvided.Entities {
-				var value = ""
-				if entity.IdPattern != "" {
-					value = "IdPattern: " + entity.IdPattern
-				}
-				if entity.ID != "" {
-					value = "Id: " + entity.ID
-				}
-				if i == 0 {
-					table.AddRow("    Entities:", value + ", Type: " + entity.Type)
-				} else {
-					table.AddRow("             ", value + ", Type: " + entity.Type)
+			return err
+		}
+
+		var raw = []json.RawMessage{}
+		if len(args) > 0 {
+			for _, id := range args {
+				registration, err := client.GetRawRegistration(cmd.Context(), id, fs, fsp)
+				if err != nil {
+					return fmt.Errorf("registration \"%s\": %w", id, err)
 				}
+				raw = append(raw, registration)
 			}
-			for i, attr := range registration.DataProvided.Attrs {
-				if i == 0 {
-					table.AddRow("    Attrs:", attr)
-				} else {
-					table.AddRow("          ", attr)
-				}
+		} else {
+			raw, err = client.GetRawRegistrations(cmd.Context(), fs, fsp)
+			if err != nil {
+				return err
 			}
-			table.AddRow("Provider:")
-			table.AddRow("    HTTP:")
-			table.AddRow("        URL:", registration.Provider.HTTP.URL)
-			table.AddRow("    LegacyForwarding:", registration.Provider.LegacyForwarding)
-			table.AddRow("    SupportedForwardingMode:", registration.Provider.SupportedForwardingMode)
-			table.AddRow("Status:", registration.Status)
-			table.AddRow("")
 		}
-		fmt.Println(table)
+		var registrations []*orionclient.Registration
+		if err := decodeResources(raw, &registrations); err != nil {
+			return err
+		}
+
+		ids := make([]string, 0, len(registrations))
+		for _, registration := range registrations {
+			ids = append(ids, registration.Id)
+		}
+		err = printResources(raw, len(args) == 1, ids, func(wide bool) *uitable.Table {
+			return describeRegistrationTable(registrations)
+		})
+		if err != nil {
+			return err
+		}
+		return nil
 	},
 }
 
 var createRegistrationCmd = &cobra.Command{
-	Use:   "registrations",
+	Use:     "registrations",
 	Aliases: []string{"registration", "regist"},
-	Short: "Create registration. Aliases: [\"registration\", \"regist\"]",
-	Long:  "Create registration resources by filename",
-	Run:  func(cmd *cobra.Command, args []string) {
-		viper.SetConfigName(registrationFile)
-		viper.SetConfigType("yml")
-		viper.AddConfigPath(".")
-		viper.AutomaticEnv()
-		if err := viper.ReadInConfig(); err != nil {
-			fmt.Println("yaml file read error")
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		if err := viper.Unmarshal(&registration); err != nil {
-			fmt.Println("registration file Unmarshal error")
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		oc := orionclient.ClientConfig{Host: config.Host, Port: config.Port, TLS: config.TLS, Token: config.Token}
-		client, err := orionclient.NewClient(oc)
+	Short:   "Create registration. Aliases: [\"registration\", \"regist\"]",
+	Long:    "Create registration resources by filename",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manifests, err := readManifests(registrationFile, recursive)
 		if err != nil {
-			panic(err)
+			return err
+		}
+		if err := requireKind(manifests, "Registration"); err != nil {
+			return err
 		}
-		registrationId, err := client.CreateRegistration(context.Background(), registration, fs, fsp)
+		client, err := newClient()
 		if err != nil {
-			fmt.Println(err)
+			return err
 		}
-		fmt.Printf("registration \"%s\" created\n", registrationId)
+		return createManifests(cmd.Context(), client, manifests)
 	},
 }
 
 var deleteRegistrationCmd = &cobra.Command{
-	Use:   "registrations",
+	Use:     "registrations",
 	Aliases: []string{"registration", "regist"},
-	Short: "Delete registration. Aliases: [\"registration\", \"regist\"]",
-	Long:  "Delete registration",
-	Args:  func(cmd *cobra.Command, args []string) error {
+	Short:   "Delete registration. Aliases: [\"registration\", \"regist\"]",
+	Long:    "Delete registration",
+	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 1 {
-			return errors.New("requires a registration ID")
+			return newUsageError("requires a registration ID")
 		}
 		return nil
 	},
-	Run:  func(cmd *cobra.Command, args []string) {
-		oc := orionclient.ClientConfig{Host: config.Host, Port: config.Port, TLS: config.TLS, Token: config.Token}
-                client, err := orionclient.NewClient(oc)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
 		if err != nil {
-                        panic(err)
-                }
+			return err
+		}
 		for _, registrationId := range args {
-			if err := client.DeleteRegistration(context.Background(), registrationId, fs, fsp); err != nil {
-				fmt.Println(err)
+			if err := client.DeleteRegistration(cmd.Context(), registrationId, fs, fsp); err != nil {
+				return fmt.Errorf("registration \"%s\": %w", registrationId, err)
 			}
 			fmt.Printf("registration \"%s\" deleted\n", registrationId)
 		}
+		return nil
 	},
 }
 
+var editRegistrationCmd = &cobra.Command{
+	Use:     "registrations ID",
+	Aliases: []string{"registration", "regist"},
+	Short:   "Edit registration. Aliases: [\"registration\", \"regist\"]",
+	Long: `Edit registration in the editor
+
+Orion cannot patch registrations, so the edited registration is created
+and the current one deleted. The registration gets a new ID.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return newUsageError("requires exactly one registration ID")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id := args[0]
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		raw, err := client.GetRawRegistration(cmd.Context(), id, fs, fsp)
+		if err != nil {
+			return fmt.Errorf("registration \"%s\": %w", id, err)
+		}
+		current, err := editableResource("Registration", raw)
+		if err != nil {
+			return err
+		}
+
+		return editResource("registration", raw, readOnlyFields("Registration"), func(data []byte) error {
+			var edited map[string]interface{}
+			m := manifest{Kind: "Registration", Source: "registration \"" + id + "\"", Data: data}
+			if err := m.decode(&edited); err != nil {
+				return err
+			}
+			if entities, _ := fieldAt(edited, "dataProvided.entities").([]interface{}); len(entities) == 0 {
+				return newUsageError("dataProvided.entities is required")
+			}
+			if stringField(edited, "provider.http.url") == "" {
+				return newUsageError("provider.http.url is required")
+			}
+			if sameJSON(normalizeResourcePair("Registration", edited, current)) {
+				fmt.Printf("registration \"%s\" unchanged\n", id)
+				return nil
+			}
+			// Orion does not implement PATCH for registrations, so the
+			// edited registration replaces the current one.
+			newID, err := client.CreateRegistration(cmd.Context(), edited, fs, fsp)
+			if err != nil {
+				return err
+			}
+			if err := client.DeleteRegistration(cmd.Context(), id, fs, fsp); err != nil {
+				return fmt.Errorf("registration \"%s\" created, but \"%s\" could not be deleted: %v", newID, id, err)
+			}
+			fmt.Printf("registration \"%s\" replaced by \"%s\"\n", id, newID)
+			return nil
+		})
+	},
+}
+
+var exportRegistrationCmd = &cobra.Command{
+	Use:     "registrations",
+	Aliases: []string{"registration", "regist"},
+	Short:   "Export registration. Aliases: [\"registration\", \"regist\"]",
+	Long:    "Export registrations as manifests",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		registrations, err := client.GetRawRegistrations(cmd.Context(), fs, fsp)
+		if err != nil {
+			return err
+		}
+		docs, err := exportResources("Registration", registrations)
+		if err != nil {
+			return err
+		}
+		return writeExport(docs)
+	},
+}
+
+func registrationTable(registrations []*orionclient.Registration, wide bool) *uitable.Table {
+	table := uitable.New()
+	table.MaxColWidth = 50
+	if wide {
+		table.AddRow("ID", "Provider URL", "Status", "Entities", "Attrs", "Forwarding Mode")
+	} else {
+		table.AddRow("ID", "Provider URL", "Status")
+	}
+	for _, registration := range registrations {
+		if wide {
+			var entities []string
+			for _, entity := range registration.DataProvided.Entities {
+				if entity.ID != "" {
+					entities = append(entities, entity.ID)
+				} else {
+					entities = append(entities, entity.IdPattern)
+				}
+			}
+			table.AddRow(registration.Id, registration.Provider.HTTP.URL, registration.Status,
+				strings.Join(entities, ","), strings.Join(registration.DataProvided.Attrs, ","), registration.Provider.SupportedForwardingMode)
+		} else {
+			table.AddRow(registration.Id, registration.Provider.HTTP.URL, registration.Status)
+		}
+	}
+	return table
+}
+
+func describeRegistrationTable(registrations []*orionclient.Registration) *uitable.Table {
+	table := uitable.New()
+	table.MaxColWidth = 80
+	table.Wrap = true
+	for _, registration := range registrations {
+		table.AddRow("ID:", registration.Id)
+		table.AddRow("DataProvided:")
+		for i, entity := range registration.DataProvided.Entities {
+			var value = ""
+			if entity.IdPattern != "" {
+				value = "IdPattern: " + entity.IdPattern
+			}
+			if entity.ID != "" {
+				value = "Id: " + entity.ID
+			}
+			if i == 0 {
+				table.AddRow("    Entities:", value+", Type: "+entity.Type)
+			} else {
+				table.AddRow("             ", value+", Type: "+entity.Type)
+			}
+		}
+		for i, attr := range registration.DataProvided.Attrs {
+			if i == 0 {
+				table.AddRow("    Attrs:", attr)
+			} else {
+				table.AddRow("          ", attr)
+			}
+		}
+		table.AddRow("Provider:")
+		table.AddRow("    HTTP:")
+		table.AddRow("        URL:", registration.Provider.HTTP.URL)
+		table.AddRow("    LegacyForwarding:", registration.Provider.LegacyForwarding)
+		table.AddRow("    SupportedForwardingMode:", registration.Provider.SupportedForwardingMode)
+		table.AddRow("Status:", registration.Status)
+		table.AddRow("")
+	}
+	return table
+}
+
 func init() {
 	getCmd.AddCommand(getRegistrationCmd)
+	addPageFlags(getRegistrationCmd)
 	describeCmd.AddCommand(describeRegistrationCmd)
-	createRegistrationCmd.Flags().StringVarP(&registrationFile, "registrationFile", "f", "", "Registration resource filename")
+	createRegistrationCmd.Flags().StringVarP(&registrationFile, "registrationFile", "f", "", "Registration resource filename, URL or - for stdin")
 	createCmd.AddCommand(createRegistrationCmd)
 	deleteCmd.AddCommand(deleteRegistrationCmd)
+	editCmd.AddCommand(editRegistrationCmd)
+	exportCmd.AddCommand(exportRegistrationCmd)
 }
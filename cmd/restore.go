@@ -0,0 +1,242 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+	"github.com/YujiAzama/orionctl/orion"
+)
+
+var dryRun bool
+var batchSize int
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore Orion resources from an archive",
+	Long: `Restore Orion resources from an archive
+
+The resources of an archive written by backup are replayed into Orion,
+entities in batches, then registrations and subscriptions. The restore is
+meant for an empty broker: entities are appended to existing ones and
+registrations and subscriptions are created again.
+
+Progress is recorded in a state file next to the archive, so running the
+same command again after an interruption resumes where it stopped. The
+state file is removed once the restore completes.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if archiveFile == "" {
+			return newUsageError("--file is required")
+		}
+		if batchSize < 1 || batchSize > orion.MaxPageSize {
+			return newUsageError("--batch-size must be between 1 and %d", orion.MaxPageSize)
+		}
+		files, err := readArchive(archiveFile)
+		if err != nil {
+			return err
+		}
+		var index backupIndex
+		if err := decodeArchiveJSON(files, "index.json", &index); err != nil {
+			return err
+		}
+		state, err := loadRestoreState(archiveFile + ".state")
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			fmt.Println(restoreTable(index, state))
+			return nil
+		}
+
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		if len(state.Done) > 0 {
+			fmt.Fprintf(os.Stderr, "resuming from %s\n", state.file)
+		}
+		for _, t := range index.Tenants {
+			if err := restoreTenant(cmd.Context(), client, files, t.tenant, state); err != nil {
+				return fmt.Errorf("%s: %w (run restore again to resume)", t.tenant, err)
+			}
+		}
+		if err := os.Remove(state.file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		fmt.Printf("restore of %s complete\n", archiveFile)
+		return nil
+	},
+}
+
+// restoreState records how many resources of each file of the archive
+// have been restored.
+type restoreState struct {
+	file string
+	Done map[string]int `json:"done"`
+}
+
+func loadRestoreState(file string) (*restoreState, error) {
+	state := &restoreState{file: file, Done: map[string]int{}}
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return state, nil
+}
+
+// advance records that n more resources of name have been restored.
+func (s *restoreState) advance(name string, n int) error {
+	s.Done[name] += n
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.file, b, 0644)
+}
+
+func restoreTenant(ctx context.Context, client *orion.Client, files map[string][]byte, t tenant, state *restoreState) error {
+	name := path.Join(t.dir(), "entities.json")
+	var entities []*orion.Entity
+	if err := decodeArchiveJSON(files, name, &entities); err != nil {
+		return err
+	}
+	for done := state.Done[name]; done < len(entities); {
+		end := done + batchSize
+		if end > len(entities) {
+			end = len(entities)
+		}
+		if err := client.BatchUpdate(ctx, orion.ActionAppend, entities[done:end], t.Service, t.ServicePath); err != nil {
+			return err
+		}
+		if err := state.advance(name, end-done); err != nil {
+			return err
+		}
+		done = end
+		fmt.Fprintf(os.Stderr, "%s: entities %d/%d\n", t, done, len(entities))
+	}
+
+	name = path.Join(t.dir(), "registrations.json")
+	var registrations []map[string]interface{}
+	if err := decodeArchiveJSON(files, name, &registrations); err != nil {
+		return err
+	}
+	for done := state.Done[name]; done < len(registrations); done++ {
+		if _, err := client.CreateRegistration(ctx, stripRuntime("Registration", registrations[done]), t.Service, t.ServicePath); err != nil {
+			return err
+		}
+		if err := state.advance(name, 1); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s: registrations %d/%d\n", t, done+1, len(registrations))
+	}
+
+	name = path.Join(t.dir(), "subscriptions.json")
+	var subscriptions []map[string]interface{}
+	if err := decodeArchiveJSON(files, name, &subscriptions); err != nil {
+		return err
+	}
+	for done := state.Done[name]; done < len(subscriptions); done++ {
+		if _, err := client.CreateSubscription(ctx, stripRuntime("Subscription", subscriptions[done]), t.Service, t.ServicePath); err != nil {
+			return err
+		}
+		if err := state.advance(name, 1); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s: subscriptions %d/%d\n", t, done+1, len(subscriptions))
+	}
+	return nil
+}
+
+func restoreTable(index backupIndex, state *restoreState) *uitable.Table {
+	count := func(t tenant, file string, total int) string {
+		if done := state.Done[path.Join(t.dir(), file)]; done > 0 {
+			return fmt.Sprintf("%d (%d restored)", total, done)
+		}
+		return fmt.Sprint(total)
+	}
+	table := uitable.New()
+	table.AddRow("Service", "Service Path", "Entities", "Registrations", "Subscriptions")
+	for _, t := range index.Tenants {
+		table.AddRow(t.Service, t.ServicePath, count(t.tenant, "entities.json", t.Entities),
+			count(t.tenant, "registrations.json", t.Registrations), count(t.tenant, "subscriptions.json", t.Subscriptions))
+	}
+	return table
+}
+
+// readArchive returns the content of every file of a tar.gz archive by
+// name.
+func readArchive(file string) (map[string][]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, newUsageError("%s: %v", file, err)
+	}
+	tr := tar.NewReader(zr)
+	files := map[string][]byte{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, newUsageError("%s: %v", file, err)
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[header.Name] = b
+	}
+}
+
+func decodeArchiveJSON(files map[string][]byte, name string, v interface{}) error {
+	b, ok := files[name]
+	if !ok {
+		return newUsageError("%s: missing from %s", name, archiveFile)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return newUsageError("%s: %v", name, err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+	restoreCmd.Flags().StringVarP(&archiveFile, "file", "f", "", "Archive written by backup")
+	restoreCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print what would be restored without changing Orion")
+	restoreCmd.Flags().IntVar(&batchSize, "batch-size", 100, "Number of entities per batch request")
+}
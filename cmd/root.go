@@ -16,8 +16,14 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
@@ -26,17 +32,48 @@ import (
 
 var cfgFile string
 var config Config
+var contextName string
 
+// currentContext is the context selected with --context or by the config
+// file, or nil when none is. configErr is set when the config file cannot
+// be read or the selected context does not exist; it is reported by
+// commands that connect to Orion.
+var currentContext *Context
+var configErr error
+
+var serverURL string
 var host string
 var port int
 var tls  bool
 var token string
+var caFile string
+var certFile string
+var keyFile string
+var insecureSkipVerify bool
+var serverName string
+var headers []string
+var timeout time.Duration
+var retries int
+var verbosity int
+var dumpCurl bool
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "orionctl",
 	Short: "This is a command line interface for control FIWARE Orion.",
-	Long:  "This is a command line interface for control FIWARE Orion.",
+	Long: `This is a command line interface for control FIWARE Orion.
+
+Exit codes:
+    0  success
+    1  unexpected error
+    2  invalid flags, arguments, queries or resource files
+    3  resource not found
+    4  authentication or authorization failure
+    5  Orion cannot be reached
+    6  live resources differ from the manifests (diff)
+  130  interrupted`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	//	Run: func(cmd *cobra.Command, args []string) { },
@@ -45,19 +82,53 @@ var rootCmd = &cobra.Command{
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	ctx := interruptContext()
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		if ctx.Err() != nil {
+			fmt.Fprintln(os.Stderr, "Interrupted")
+			os.Exit(exitInterrupted)
+		}
+		var status *exitStatus
+		if !errors.As(err, &status) {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+		os.Exit(exitCode(err))
 	}
 }
 
+// interruptContext returns a context that is cancelled by the first
+// SIGINT or SIGTERM, which cancels the requests in flight. Further signals
+// terminate orionctl at once. Signals are ignored while an editor runs, as
+// they are meant for the editor.
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for range signals {
+			if atomic.LoadInt32(&editing) == 0 {
+				break
+			}
+		}
+		cancel()
+		signal.Stop(signals)
+	}()
+	return ctx
+}
+
 func init() {
 	cobra.OnInitialize(initConfig)
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &usageError{err: err}
+	})
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.orionctl.yaml)")
+	rootCmd.PersistentFlags().StringVar(&contextName, "context", "", "Context of the config file to use instead of the current one")
 
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 
+	rootCmd.PersistentFlags().StringVar(&serverURL, "url", "", "Orion URL including any path prefix, e.g. https://gateway.example/orion/ (takes precedence over --host, --port and --tls)")
+	viper.BindPFlag("Server", rootCmd.PersistentFlags().Lookup("url"))
 	rootCmd.PersistentFlags().StringVarP(&host, "host", "H", "localhost", "Orion hostname or IP address")
 	viper.BindPFlag("Host", rootCmd.PersistentFlags().Lookup("host"))
 	rootCmd.PersistentFlags().IntVarP(&port, "port", "p", 1026, "Orion port number")
@@ -66,9 +137,21 @@ func init() {
 	viper.BindPFlag("TLS", rootCmd.PersistentFlags().Lookup("tls"))
 	rootCmd.PersistentFlags().StringVarP(&token, "token", "T", "", "Access Token")
 	viper.BindPFlag("Token", rootCmd.PersistentFlags().Lookup("token"))
+	rootCmd.PersistentFlags().StringVar(&caFile, "ca-file", "", "PEM bundle of certificate authorities to trust in addition to the system ones")
+	rootCmd.PersistentFlags().StringVar(&certFile, "cert-file", "", "PEM client certificate for mutual TLS")
+	rootCmd.PersistentFlags().StringVar(&keyFile, "key-file", "", "PEM key of the client certificate")
+	rootCmd.PersistentFlags().BoolVar(&insecureSkipVerify, "insecure-skip-verify", false, "Do not verify the certificate of Orion (insecure)")
+	rootCmd.PersistentFlags().StringVar(&serverName, "server-name", "", "Server name to send with SNI and to verify the certificate of Orion against")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Maximum time of a request to Orion including its retries, e.g. 30s (0 for no limit)")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", 0, "Times to retry GET, DELETE and PATCH requests that fail with a network error or 429, 502, 503 or 504")
+	rootCmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "Log requests to Orion to stderr: -v for methods, URLs, Fiware headers and timing, -vv for all headers, -vvv for bodies")
+	rootCmd.PersistentFlags().BoolVar(&dumpCurl, "dump-curl", false, "Print an equivalent curl command for every request to Orion to stderr")
+	rootCmd.PersistentFlags().StringArrayVar(&headers, "header", nil, "Extra header \"Name: value\" to send with every request to Orion, can be repeated")
 
 	rootCmd.PersistentFlags().StringVarP(&fs, "fiware-service", "s", "", "FIWARE Service")
 	rootCmd.PersistentFlags().StringVarP(&fsp, "fiware-servicepath", "P", "", "FIWARE Service Path")
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", "", "Output format. One of: json, yaml, wide, name, jsonpath=..., go-template=... (get entities also supports geojson)")
+	rootCmd.PersistentFlags().StringVar(&templateFile, "template-file", "", "Template file for -o jsonpath or -o go-template")
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -91,13 +174,99 @@ func initConfig() {
 
 	viper.AutomaticEnv() // read in environment variables that match
 
-	// If a config file is found, read it in.
+	// If a config file is found, read it in. It is optional unless given
+	// with --config.
 	if err := viper.ReadInConfig(); err != nil {
-		fmt.Println(err)
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			configErr = &usageError{err: fmt.Errorf("config file: %w", err)}
+		}
 	}
 
 	if err := viper.Unmarshal(&config); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		configErr = &usageError{err: fmt.Errorf("config file %s: %w", viper.ConfigFileUsed(), err)}
+		return
+	}
+	useContext()
+
+	// --host, --port and --tls replace a server URL of the config file.
+	if !rootCmd.PersistentFlags().Lookup("url").Changed && addressFlagsChanged() {
+		config.Server = ""
+	}
+}
+
+// addressFlagsChanged reports whether --host, --port or --tls was given.
+func addressFlagsChanged() bool {
+	flags := rootCmd.PersistentFlags()
+	return flags.Lookup("host").Changed || flags.Lookup("port").Changed || flags.Lookup("tls").Changed
+}
+
+// useContext applies the selected context to the settings that were not
+// given as flags.
+func useContext() {
+	name := contextName
+	if name == "" {
+		name = config.CurrentContext
+	}
+	if name == "" {
+		return
+	}
+	ctx, ok := config.context(name)
+	if !ok {
+		configErr = newUsageError("context \"%s\" not found in %s", name, viper.ConfigFileUsed())
+		return
+	}
+	currentContext = ctx
+
+	flags := rootCmd.PersistentFlags()
+	if !flags.Lookup("url").Changed {
+		switch {
+		case ctx.Server != "":
+			config.Server = ctx.Server
+		case ctx.Host != "" || ctx.Port != 0:
+			config.Server = ""
+		}
+	}
+	if !flags.Lookup("host").Changed && ctx.Host != "" {
+		config.Host = ctx.Host
+	}
+	if !flags.Lookup("port").Changed && ctx.Port != 0 {
+		config.Port = ctx.Port
+	}
+	if !flags.Lookup("tls").Changed && ctx.TLS != nil {
+		config.TLS = *ctx.TLS
+	}
+	if !flags.Lookup("token").Changed {
+		switch {
+		case ctx.Token != "":
+			config.Token = ctx.Token
+		case ctx.Auth != nil && ctx.Auth.Type != "":
+			// The context acquires its own tokens.
+			config.Token = ""
+		}
+	}
+	if ctx.Headers != nil {
+		config.Headers = ctx.Headers
+	}
+	if !flags.Lookup("fiware-service").Changed {
+		fs = ctx.Service
+	}
+	if !flags.Lookup("fiware-servicepath").Changed {
+		fsp = ctx.ServicePath
+	}
+	if !flags.Lookup("ca-file").Changed {
+		caFile = ctx.CAFile
+	}
+	if !flags.Lookup("cert-file").Changed {
+		certFile = ctx.CertFile
+	}
+	if !flags.Lookup("key-file").Changed {
+		keyFile = ctx.KeyFile
+	}
+	if !flags.Lookup("insecure-skip-verify").Changed {
+		insecureSkipVerify = ctx.InsecureSkipVerify
+	}
+	if !flags.Lookup("server-name").Changed {
+		serverName = ctx.ServerName
 	}
 }
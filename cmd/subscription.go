@@ -17,211 +17,608 @@ package cmd
 
 import (
 	"context"
-	"errors"
+	"encoding/json"
 	"fmt"
-	"os"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/YujiAzama/orionclient-go/orionclient"
+	"github.com/YujiAzama/orionctl/orion"
 	"github.com/gosuri/uitable"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
-	"github.com/YujiAzama/orionclient-go/orionclient"
 )
 
 var subsFile string
-var subscription orionclient.Subscription
+var subsURL string
+var subsThrottling int
+var subsExpires string
+var subsAttrs []string
+var subsDescription string
+var subsDescriptionFilter string
+var subsURLFilter string
+var renewFor string
 
 var getSubscriptionCmd = &cobra.Command{
-	Use:   "subscriptions",
+	Use:     "subscriptions",
 	Aliases: []string{"subscription", "subs"},
-	Short: "Get subscription. Aliases: [\"subscription\", \"subs\"]",
-	Long:  "Get subscription",
-	Run: func(cmd *cobra.Command, args []string) {
-		oc := orionclient.ClientConfig{Host: config.Host, Port: config.Port, TLS: config.TLS, Token: config.Token}
-		client, err := orionclient.NewClient(oc)
+	Short:   "Get subscription. Aliases: [\"subscription\", \"subs\"]",
+	Long:    "Get subscription",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		maxResults, err := pageLimit()
 		if err != nil {
-			panic(err)
+			return err
 		}
 
-		var subscriptions = []*orionclient.Subscription{}
+		var raw = []json.RawMessage{}
+		total := 0
 		if len(args) > 0 {
 			for _, id := range args {
-				subscription, err := client.GetSubscription(context.Background(), id, fs, fsp)
+				subscription, err := client.GetRawSubscription(cmd.Context(), id, fs, fsp)
 				if err != nil {
-					panic(err)
+					return fmt.Errorf("subscription \"%s\": %w", id, err)
 				}
-				subscriptions = append(subscriptions, subscription)
+				raw = append(raw, subscription)
 			}
 		} else {
-			allSubscriptions, err := client.GetSubscriptions(context.Background(), fs, fsp)
+			total, err = orion.Paginate(maxResults, offset, func(page orion.Page) (int, int, error) {
+				items, count, err := client.GetRawSubscriptionsPage(cmd.Context(), page, fs, fsp)
+				raw = append(raw, items...)
+				return len(items), count, err
+			})
 			if err != nil {
-				panic(err)
+				return err
 			}
-			subscriptions = allSubscriptions
+		}
+		var subscriptions []*orionclient.Subscription
+		if err := decodeResources(raw, &subscriptions); err != nil {
+			return err
 		}
 
-		table := uitable.New()
-		table.MaxColWidth = 50
-		table.AddRow("ID", "Description", "Notification URL", "LastSuccess")
+		ids := make([]string, 0, len(subscriptions))
 		for _, subscription := range subscriptions {
-			table.AddRow(subscription.Id, subscription.Description, subscription.Notification.HTTP.URL, subscription.Notification.LastSuccess)
+			ids = append(ids, subscription.Id)
 		}
-		fmt.Println(table)
+		err = printResources(raw, len(args) == 1, ids, func(wide bool) *uitable.Table {
+			return subscriptionTable(subscriptions, wide)
+		})
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 && isTableOutput() {
+			printTotal(len(subscriptions), total)
+		}
+		return nil
 	},
 }
 
 var describeSubscriptionCmd = &cobra.Command{
-	Use:   "subscriptions",
+	Use:     "subscriptions",
 	Aliases: []string{"subscription", "subs"},
-	Short: "Describe subscription. Aliases: [\"subscription\", \"subs\"]",
-	Long:  "Describe subscription",
-	Run: func(cmd *cobra.Command, args []string) {
-		oc := orionclient.ClientConfig{Host: config.Host, Port: config.Port, TLS: config.TLS, Token: config.Token}
-		client, err := orionclient.NewClient(oc)
+	Short:   "Describe subscription. Aliases: [\"subscription\", \"subs\"]",
+	Long:    "Describe subscription",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
 		if err != nil {
-			panic(err)
+			return err
 		}
 
-		var subscriptions = []*orionclient.Subscription{}
+		var raw = []json.RawMessage{}
 		if len(args) > 0 {
 			for _, id := range args {
-				subscription, err := client.GetSubscription(context.Background(), id, fs, fsp)
+				subscription, err := client.GetRawSubscription(cmd.Context(), id, fs, fsp)
 				if err != nil {
-					panic(err)
+					return fmt.Errorf("subscription \"%s\": %w", id, err)
 				}
-				subscriptions = append(subscriptions, subscription)
+				raw = append(raw, subscription)
 			}
 		} else {
-			allSubscriptions, err := client.GetSubscriptions(context.Background(), fs, fsp)
+			raw, err = client.GetRawSubscriptions(cmd.Context(), fs, fsp)
 			if err != nil {
-				panic(err)
+				return err
 			}
-			subscriptions = allSubscriptions
+		}
+		var subscriptions []*orionclient.Subscription
+		if err := decodeResources(raw, &subscriptions); err != nil {
+			return err
 		}
 
-		table := uitable.New()
-		table.MaxColWidth = 80
-		table.Wrap = true
+		ids := make([]string, 0, len(subscriptions))
 		for _, subscription := range subscriptions {
-			table.AddRow("ID:", subscription.Id)
-			table.AddRow("Description:", subscription.Description)
-			table.AddRow("Subject:")
-			for i, entity := range subscription.Subject.Entities {
-				var value = ""
-				if entity.IdPattern != "" {
-					value = "IdPattern: " + entity.IdPattern
-				}
-				if entity.ID != "" {
-					value = "Id: " + entity.ID
-				}
-				if i == 0 {
-					table.AddRow("    Entities:", value + ", Type: " + entity.Type)
-				} else {
-					table.AddRow("             ", value + ", Type: " + entity.Type)
-				}
-			}
-			if len(subscription.Subject.Condition.Attrs) > 0 || subscription.Subject.Condition.Expression != nil {
-				table.AddRow("    Condition:")
-				for i, attr := range subscription.Subject.Condition.Attrs {
-					if i == 0 {
-						table.AddRow("        Attrs:", attr)
-					} else {
-						table.AddRow("              ", attr)
-					}
-				}
-				if subscription.Subject.Condition.Expression != nil {
-					table.AddRow("        Expression:")
-					table.AddRow("            Q:", subscription.Subject.Condition.Expression.Q)
-				}
-			}
-			table.AddRow("Notification:")
-			table.AddRow("    HTTP:")
-			table.AddRow("        URL:", subscription.Notification.HTTP.URL)
-			for i, attr := range subscription.Notification.Attrs {
-				if i == 0 {
-					table.AddRow("    Attrs:", attr)
-				} else {
-					table.AddRow("          ", attr)
-				}
-			}
-			table.AddRow("    AttrsFormat:", subscription.Notification.AttrsFormat)
-			table.AddRow("    LastFailure:", subscription.Notification.LastFailure)
-			table.AddRow("    LastFailureReason:", subscription.Notification.LastFailureReason)
-			table.AddRow("    LastNotification:", subscription.Notification.LastNotification)
-			table.AddRow("    LastSuccess:", subscription.Notification.LastSuccess)
-			table.AddRow("    LastSuccessCode:", subscription.Notification.LastSuccessCode)
-			table.AddRow("    OnlyChangedAttrs:", subscription.Notification.OnlyChangedAttrs)
-			table.AddRow("    TimesSent:", subscription.Notification.TimesSent)
-			table.AddRow("Expires:", subscription.Expires)
-			table.AddRow("Throttling:", subscription.Throttling)
-			table.AddRow("")
+			ids = append(ids, subscription.Id)
 		}
-		fmt.Println(table)
+		err = printResources(raw, len(args) == 1, ids, func(wide bool) *uitable.Table {
+			return describeSubscriptionTable(subscriptions)
+		})
+		if err != nil {
+			return err
+		}
+		return nil
 	},
 }
 
 var createSubscriptionCmd = &cobra.Command{
-	Use:   "subscriptions",
+	Use:     "subscriptions",
 	Aliases: []string{"subscription", "subs"},
-	Short: "Create subscription. Aliases: [\"subscription\", \"subs\"]",
-	Long:  "Create subscription resources by filename",
-	Run:  func(cmd *cobra.Command, args []string) {
-		viper.SetConfigName(subsFile)
-		viper.SetConfigType("yml")
-		viper.AddConfigPath(".")
-		viper.AutomaticEnv()
-		if err := viper.ReadInConfig(); err != nil {
-			fmt.Println("yaml file read error")
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		if err := viper.Unmarshal(&subscription); err != nil {
-			fmt.Println("subscription file Unmarshal error")
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		oc := orionclient.ClientConfig{Host: config.Host, Port: config.Port, TLS: config.TLS, Token: config.Token}
-		client, err := orionclient.NewClient(oc)
+	Short:   "Create subscription. Aliases: [\"subscription\", \"subs\"]",
+	Long:    "Create subscription resources by filename",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manifests, err := readManifests(subsFile, recursive)
 		if err != nil {
-			panic(err)
+			return err
+		}
+		if err := requireKind(manifests, "Subscription"); err != nil {
+			return err
 		}
-		subscriptionId, err := client.CreateSubscription(context.Background(), subscription, fs, fsp)
+		client, err := newClient()
 		if err != nil {
-			fmt.Println(err)
+			return err
 		}
-		fmt.Printf("subscription \"%s\" created\n", subscriptionId)
+		return createManifests(cmd.Context(), client, manifests)
 	},
 }
 
 var deleteSubscriptionCmd = &cobra.Command{
-	Use:   "subscriptions",
+	Use:     "subscriptions",
 	Aliases: []string{"subscription", "subs"},
-	Short: "Delete subscription. Aliases: [\"subscription\", \"subs\"]",
-	Long:  "Delete subscription",
-	Args:  func(cmd *cobra.Command, args []string) error {
+	Short:   "Delete subscription. Aliases: [\"subscription\", \"subs\"]",
+	Long:    "Delete subscription",
+	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 1 {
-			return errors.New("requires a subscription ID")
+			return newUsageError("requires a subscription ID")
 		}
 		return nil
 	},
-	Run:  func(cmd *cobra.Command, args []string) {
-		oc := orionclient.ClientConfig{Host: config.Host, Port: config.Port, TLS: config.TLS, Token: config.Token}
-                client, err := orionclient.NewClient(oc)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
 		if err != nil {
-                        panic(err)
-                }
+			return err
+		}
 		for _, subscriptionId := range args {
-			if err := client.DeleteSubscription(context.Background(), subscriptionId, fs, fsp); err != nil {
-				fmt.Println(err)
+			if err := client.DeleteSubscription(cmd.Context(), subscriptionId, fs, fsp); err != nil {
+				return fmt.Errorf("subscription \"%s\": %w", subscriptionId, err)
 			}
 			fmt.Printf("subscription \"%s\" deleted\n", subscriptionId)
 		}
+		return nil
 	},
 }
 
+var updateSubscriptionCmd = &cobra.Command{
+	Use:     "subscriptions ID",
+	Aliases: []string{"subscription", "subs"},
+	Short:   "Update subscription. Aliases: [\"subscription\", \"subs\"]",
+	Long: `Update subscription
+
+The fields given in the file passed with -f and the flags are patched into
+the subscription, which keeps its ID and notification statistics. Objects
+in the file are merged into the current subscription, lists replace it.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return newUsageError("requires exactly one subscription ID")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id := args[0]
+		flags := cmd.Flags()
+		if subsFile == "" && !flags.Changed("notification-url") && !flags.Changed("attrs") && !flags.Changed("throttling") &&
+			!flags.Changed("expires") && !flags.Changed("description") {
+			return newUsageError("nothing to update, use -f or one of the field flags")
+		}
+		var manifests []manifest
+		if subsFile != "" {
+			var err error
+			manifests, err = readManifests(subsFile, false)
+			if err != nil {
+				return err
+			}
+			if err := requireKind(manifests, "Subscription"); err != nil {
+				return err
+			}
+			if len(manifests) != 1 {
+				return newUsageError("%s: expected a single subscription, got %d", subsFile, len(manifests))
+			}
+		}
+		if flags.Changed("expires") {
+			if _, err := time.Parse(time.RFC3339, subsExpires); err != nil {
+				return newUsageError("invalid --expires \"%s\", must be an RFC 3339 time such as 2040-01-01T00:00:00Z", subsExpires)
+			}
+		}
+
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		raw, err := client.GetRawSubscription(cmd.Context(), id, fs, fsp)
+		if err != nil {
+			return fmt.Errorf("subscription \"%s\": %w", id, err)
+		}
+		current, err := editableResource("Subscription", raw)
+		if err != nil {
+			return err
+		}
+
+		after := copyValue(current).(map[string]interface{})
+		if len(manifests) == 1 {
+			var fields map[string]interface{}
+			if err := manifests[0].decode(&fields); err != nil {
+				return err
+			}
+			mergeFields(after, fields)
+		}
+		if flags.Changed("notification-url") {
+			if fieldAt(after, "notification.httpCustom") != nil {
+				setField(after, "notification.httpCustom.url", subsURL)
+			} else {
+				setField(after, "notification.http.url", subsURL)
+			}
+		}
+		if flags.Changed("attrs") {
+			setField(after, "notification.attrs", subsAttrs)
+		}
+		if flags.Changed("throttling") {
+			after["throttling"] = subsThrottling
+		}
+		if flags.Changed("expires") {
+			after["expires"] = subsExpires
+		}
+		if flags.Changed("description") {
+			after["description"] = subsDescription
+		}
+
+		patch, err := patchFields(current, after)
+		if err != nil {
+			return err
+		}
+		if len(patch) == 0 {
+			fmt.Printf("subscription \"%s\" unchanged\n", id)
+			return nil
+		}
+		if err := client.PatchSubscription(cmd.Context(), id, patch, fs, fsp); err != nil {
+			return fmt.Errorf("subscription \"%s\": %w", id, err)
+		}
+		raw, err = client.GetRawSubscription(cmd.Context(), id, fs, fsp)
+		if err != nil {
+			return fmt.Errorf("subscription \"%s\": %w", id, err)
+		}
+		updated, err := editableResource("Subscription", raw)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("subscription \"%s\" updated\n", id)
+		table, err := changeTable(current, updated)
+		if err != nil {
+			return err
+		}
+		fmt.Println(table)
+		return nil
+	},
+}
+
+var pauseSubscriptionCmd = &cobra.Command{
+	Use:     "subscriptions [ID...]",
+	Aliases: []string{"subscription", "subs"},
+	Short:   "Pause subscription. Aliases: [\"subscription\", \"subs\"]",
+	Long: `Pause subscription
+
+Paused subscriptions are kept but send no notifications until they are
+resumed. Subscriptions are selected by ID, with --all, or by their
+description or notification URL.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return patchSubscriptions(cmd.Context(), args, map[string]interface{}{"status": "inactive"})
+	},
+}
+
+var resumeSubscriptionCmd = &cobra.Command{
+	Use:     "subscriptions [ID...]",
+	Aliases: []string{"subscription", "subs"},
+	Short:   "Resume subscription. Aliases: [\"subscription\", \"subs\"]",
+	Long: `Resume subscription
+
+Subscriptions are selected by ID, with --all, or by their description or
+notification URL.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return patchSubscriptions(cmd.Context(), args, map[string]interface{}{"status": "active"})
+	},
+}
+
+var renewSubscriptionCmd = &cobra.Command{
+	Use:     "subscriptions [ID...]",
+	Aliases: []string{"subscription", "subs"},
+	Short:   "Renew subscription. Aliases: [\"subscription\", \"subs\"]",
+	Long: `Renew subscription
+
+The subscriptions expire the given duration from now, such as 12h, 30d or
+2w. Subscriptions are selected by ID, with --all, or by their description
+or notification URL.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if renewFor == "" {
+			return newUsageError("--for is required")
+		}
+		duration, err := parseDuration(renewFor)
+		if err != nil {
+			return err
+		}
+		expires := time.Now().Add(duration).UTC().Format(orionTimeFormat)
+		return patchSubscriptions(cmd.Context(), args, map[string]interface{}{"expires": expires})
+	},
+}
+
+// patchSubscriptions applies patch to the selected subscriptions and prints
+// them as they are afterwards.
+func patchSubscriptions(ctx context.Context, args []string, patch map[string]interface{}) error {
+	filtered := subsDescriptionFilter != "" || subsURLFilter != ""
+	switch {
+	case len(args) > 0 && (all || filtered):
+		return newUsageError("subscription IDs cannot be combined with --all, --description or --url-contains")
+	case all && filtered:
+		return newUsageError("--all cannot be combined with --description or --url-contains")
+	case len(args) == 0 && !all && !filtered:
+		return newUsageError("requires subscription IDs, --all, --description or --url-contains")
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	var ids []string
+	if len(args) > 0 {
+		for _, id := range args {
+			if _, err := client.GetRawSubscription(ctx, id, fs, fsp); err != nil {
+				return fmt.Errorf("subscription \"%s\": %w", id, err)
+			}
+		}
+		ids = args
+	} else {
+		raw, err := client.GetRawSubscriptions(ctx, fs, fsp)
+		if err != nil {
+			return err
+		}
+		for _, item := range raw {
+			subscription, err := decodeResource(item)
+			if err != nil {
+				return err
+			}
+			if strings.Contains(stringField(subscription, "description"), subsDescriptionFilter) &&
+				strings.Contains(notificationURL(subscription), subsURLFilter) {
+				ids = append(ids, stringField(subscription, "id"))
+			}
+		}
+		if len(ids) == 0 {
+			return fmt.Errorf("no subscriptions match")
+		}
+	}
+
+	patched := make([]json.RawMessage, 0, len(ids))
+	subscriptions := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		if err := client.PatchSubscription(ctx, id, patch, fs, fsp); err != nil {
+			return fmt.Errorf("subscription \"%s\": %w", id, err)
+		}
+		updated, err := client.GetRawSubscription(ctx, id, fs, fsp)
+		if err != nil {
+			return fmt.Errorf("subscription \"%s\": %w", id, err)
+		}
+		subscription, err := decodeResource(updated)
+		if err != nil {
+			return err
+		}
+		patched = append(patched, updated)
+		subscriptions = append(subscriptions, subscription)
+	}
+	return printResources(patched, len(args) == 1, ids, func(wide bool) *uitable.Table {
+		return subscriptionStatusTable(subscriptions)
+	})
+}
+
+func subscriptionStatusTable(subscriptions []map[string]interface{}) *uitable.Table {
+	table := uitable.New()
+	table.MaxColWidth = 50
+	table.AddRow("ID", "Description", "Notification URL", "Status", "Expires")
+	for _, subscription := range subscriptions {
+		table.AddRow(stringField(subscription, "id"), stringField(subscription, "description"), notificationURL(subscription),
+			stringField(subscription, "status"), stringField(subscription, "expires"))
+	}
+	return table
+}
+
+var editSubscriptionCmd = &cobra.Command{
+	Use:     "subscriptions ID",
+	Aliases: []string{"subscription", "subs"},
+	Short:   "Edit subscription. Aliases: [\"subscription\", \"subs\"]",
+	Long:    "Edit subscription in the editor and patch the changes",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return newUsageError("requires exactly one subscription ID")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id := args[0]
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		raw, err := client.GetRawSubscription(cmd.Context(), id, fs, fsp)
+		if err != nil {
+			return fmt.Errorf("subscription \"%s\": %w", id, err)
+		}
+		current, err := editableResource("Subscription", raw)
+		if err != nil {
+			return err
+		}
+
+		return editResource("subscription", raw, readOnlyFields("Subscription"), func(data []byte) error {
+			var edited map[string]interface{}
+			m := manifest{Kind: "Subscription", Source: "subscription \"" + id + "\"", Data: data}
+			if err := m.decode(&edited); err != nil {
+				return err
+			}
+			if entities, _ := fieldAt(edited, "subject.entities").([]interface{}); len(entities) == 0 {
+				return newUsageError("subject.entities is required")
+			}
+			if fieldAt(edited, "notification.http") == nil && fieldAt(edited, "notification.httpCustom") == nil &&
+				fieldAt(edited, "notification.mqtt") == nil && fieldAt(edited, "notification.mqttCustom") == nil {
+				return newUsageError("notification.http, httpCustom, mqtt or mqttCustom is required")
+			}
+			var removed []string
+			for name := range current {
+				if _, ok := edited[name]; !ok {
+					removed = append(removed, name)
+				}
+			}
+			if len(removed) > 0 {
+				sort.Strings(removed)
+				return newUsageError("fields cannot be removed by editing a subscription: %s. Use apply to replace the subscription without them", strings.Join(removed, ", "))
+			}
+			patch, err := patchFields(current, edited)
+			if err != nil {
+				return err
+			}
+			if len(patch) == 0 {
+				fmt.Printf("subscription \"%s\" unchanged\n", id)
+				return nil
+			}
+			if err := client.PatchSubscription(cmd.Context(), id, patch, fs, fsp); err != nil {
+				return err
+			}
+			fmt.Printf("subscription \"%s\" edited\n", id)
+			return nil
+		})
+	},
+}
+
+var exportSubscriptionCmd = &cobra.Command{
+	Use:     "subscriptions",
+	Aliases: []string{"subscription", "subs"},
+	Short:   "Export subscription. Aliases: [\"subscription\", \"subs\"]",
+	Long:    "Export subscriptions as manifests",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		subscriptions, err := client.GetRawSubscriptions(cmd.Context(), fs, fsp)
+		if err != nil {
+			return err
+		}
+		docs, err := exportResources("Subscription", subscriptions)
+		if err != nil {
+			return err
+		}
+		return writeExport(docs)
+	},
+}
+
+func subscriptionTable(subscriptions []*orionclient.Subscription, wide bool) *uitable.Table {
+	table := uitable.New()
+	table.MaxColWidth = 50
+	if wide {
+		table.AddRow("ID", "Description", "Notification URL", "LastSuccess", "Status", "Expires", "Throttling", "TimesSent")
+	} else {
+		table.AddRow("ID", "Description", "Notification URL", "LastSuccess")
+	}
+	for _, subscription := range subscriptions {
+		if wide {
+			table.AddRow(subscription.Id, subscription.Description, subscription.Notification.HTTP.URL, subscription.Notification.LastSuccess,
+				subscription.Status, subscription.Expires, subscription.Throttling, subscription.Notification.TimesSent)
+		} else {
+			table.AddRow(subscription.Id, subscription.Description, subscription.Notification.HTTP.URL, subscription.Notification.LastSuccess)
+		}
+	}
+	return table
+}
+
+func describeSubscriptionTable(subscriptions []*orionclient.Subscription) *uitable.Table {
+	table := uitable.New()
+	table.MaxColWidth = 80
+	table.Wrap = true
+	for _, subscription := range subscriptions {
+		table.AddRow("ID:", subscription.Id)
+		table.AddRow("Description:", subscription.Description)
+		table.AddRow("Subject:")
+		for i, entity := range subscription.Subject.Entities {
+			var value = ""
+			if entity.IdPattern != "" {
+				value = "IdPattern: " + entity.IdPattern
+			}
+			if entity.ID != "" {
+				value = "Id: " + entity.ID
+			}
+			if i == 0 {
+				table.AddRow("    Entities:", value+", Type: "+entity.Type)
+			} else {
+				table.AddRow("             ", value+", Type: "+entity.Type)
+			}
+		}
+		if condition := subscription.Subject.Condition; condition != nil && (len(condition.Attrs) > 0 || condition.Expression != nil) {
+			table.AddRow("    Condition:")
+			for i, attr := range subscription.Subject.Condition.Attrs {
+				if i == 0 {
+					table.AddRow("        Attrs:", attr)
+				} else {
+					table.AddRow("              ", attr)
+				}
+			}
+			if subscription.Subject.Condition.Expression != nil {
+				table.AddRow("        Expression:")
+				table.AddRow("            Q:", subscription.Subject.Condition.Expression.Q)
+			}
+		}
+		table.AddRow("Notification:")
+		table.AddRow("    HTTP:")
+		table.AddRow("        URL:", subscription.Notification.HTTP.URL)
+		for i, attr := range subscription.Notification.Attrs {
+			if i == 0 {
+				table.AddRow("    Attrs:", attr)
+			} else {
+				table.AddRow("          ", attr)
+			}
+		}
+		table.AddRow("    AttrsFormat:", subscription.Notification.AttrsFormat)
+		table.AddRow("    LastFailure:", subscription.Notification.LastFailure)
+		table.AddRow("    LastFailureReason:", subscription.Notification.LastFailureReason)
+		table.AddRow("    LastNotification:", subscription.Notification.LastNotification)
+		table.AddRow("    LastSuccess:", subscription.Notification.LastSuccess)
+		table.AddRow("    LastSuccessCode:", subscription.Notification.LastSuccessCode)
+		table.AddRow("    OnlyChangedAttrs:", subscription.Notification.OnlyChangedAttrs)
+		table.AddRow("    TimesSent:", subscription.Notification.TimesSent)
+		table.AddRow("Expires:", subscription.Expires)
+		table.AddRow("Throttling:", subscription.Throttling)
+		table.AddRow("")
+	}
+	return table
+}
+
 func init() {
 	getCmd.AddCommand(getSubscriptionCmd)
+	addPageFlags(getSubscriptionCmd)
 	describeCmd.AddCommand(describeSubscriptionCmd)
-	createSubscriptionCmd.Flags().StringVarP(&subsFile, "subsFile", "f", "", "Subscription resource filename")
+	createSubscriptionCmd.Flags().StringVarP(&subsFile, "subsFile", "f", "", "Subscription resource filename, URL or - for stdin")
 	createCmd.AddCommand(createSubscriptionCmd)
 	deleteCmd.AddCommand(deleteSubscriptionCmd)
+	updateSubscriptionCmd.Flags().StringVarP(&subsFile, "subsFile", "f", "", "Subscription patch filename, URL or - for stdin")
+	updateSubscriptionCmd.Flags().StringVar(&subsURL, "notification-url", "", "Notification URL")
+	updateSubscriptionCmd.Flags().IntVar(&subsThrottling, "throttling", 0, "Minimum seconds between notifications")
+	updateSubscriptionCmd.Flags().StringVar(&subsExpires, "expires", "", "Expiration time in RFC 3339 format")
+	updateSubscriptionCmd.Flags().StringSliceVar(&subsAttrs, "attrs", nil, "Comma separated list of attributes to notify")
+	updateSubscriptionCmd.Flags().StringVar(&subsDescription, "description", "", "Subscription description")
+	updateCmd.AddCommand(updateSubscriptionCmd)
+	for _, cmd := range []*cobra.Command{pauseSubscriptionCmd, resumeSubscriptionCmd, renewSubscriptionCmd} {
+		cmd.Flags().BoolVar(&all, "all", false, "Select every subscription")
+		cmd.Flags().StringVar(&subsDescriptionFilter, "description", "", "Select subscriptions whose description contains this text")
+		cmd.Flags().StringVar(&subsURLFilter, "url-contains", "", "Select subscriptions whose notification URL contains this text")
+	}
+	renewSubscriptionCmd.Flags().StringVar(&renewFor, "for", "", "Duration until the subscriptions expire, such as 12h, 30d or 2w")
+	editCmd.AddCommand(editSubscriptionCmd)
+	exportCmd.AddCommand(exportSubscriptionCmd)
+	pauseCmd.AddCommand(pauseSubscriptionCmd)
+	resumeCmd.AddCommand(resumeSubscriptionCmd)
+	renewCmd.AddCommand(renewSubscriptionCmd)
 }
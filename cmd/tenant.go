@@ -0,0 +1,71 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"path"
+	"strings"
+)
+
+// tenant is a Fiware service and service path.
+type tenant struct {
+	Service     string `json:"service"`
+	ServicePath string `json:"servicePath"`
+}
+
+func (t tenant) String() string {
+	return t.Service + ":" + t.ServicePath
+}
+
+// dir returns the archive directory of the tenant.
+func (t tenant) dir() string {
+	service := t.Service
+	if service == "" {
+		service = "_default"
+	}
+	return path.Join(service, t.ServicePath)
+}
+
+// currentTenant returns the service and service path given with -s and
+// -P.
+func currentTenant() tenant {
+	return tenant{Service: fs, ServicePath: fsp}
+}
+
+// parseTenants parses SERVICE[:PATH] values, defaulting to the service
+// and service path given with -s and -P when values is empty.
+func parseTenants(values []string) ([]tenant, error) {
+	if len(values) == 0 {
+		servicePath := fsp
+		if servicePath == "" {
+			servicePath = "/"
+		}
+		return []tenant{{Service: fs, ServicePath: servicePath}}, nil
+	}
+	var tenants []tenant
+	for _, value := range values {
+		t := tenant{Service: value, ServicePath: "/"}
+		if i := strings.Index(value, ":"); i >= 0 {
+			t.Service, t.ServicePath = value[:i], value[i+1:]
+		}
+		if !strings.HasPrefix(t.ServicePath, "/") || strings.Contains(t.ServicePath, "#") {
+			return nil, newUsageError("invalid tenant \"%s\", expected SERVICE[:PATH] with an absolute service path", value)
+		}
+		t.ServicePath = path.Clean(t.ServicePath)
+		tenants = append(tenants, t)
+	}
+	return tenants, nil
+}
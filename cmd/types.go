@@ -1,8 +1,59 @@
 package cmd
 
+// Config is the content of the config file. Server, Host, Port, TLS, Token
+// and Headers are used when no context is selected.
 type Config struct {
-	Host	string
-	Port	int
-	TLS	bool
-	Token	string
+	Server         string            `json:"server,omitempty" yaml:"server,omitempty"`
+	Host           string            `json:"host,omitempty" yaml:"host,omitempty"`
+	Port           int               `json:"port,omitempty" yaml:"port,omitempty"`
+	TLS            bool              `json:"tls,omitempty" yaml:"tls,omitempty"`
+	Token          string            `json:"token,omitempty" yaml:"token,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	CurrentContext string            `mapstructure:"current-context" json:"current-context,omitempty" yaml:"current-context,omitempty"`
+	Contexts       []Context         `json:"contexts,omitempty" yaml:"contexts,omitempty"`
+}
+
+// Context is a named broker and tenant. Server is the URL of Orion,
+// including any path prefix, and takes precedence over Host, Port and TLS.
+// TLS is nil when the context leaves it to the top level setting. Headers
+// are sent with every request to Orion.
+type Context struct {
+	Name        string            `json:"name" yaml:"name"`
+	Server      string            `json:"server,omitempty" yaml:"server,omitempty"`
+	Host        string            `json:"host,omitempty" yaml:"host,omitempty"`
+	Port        int               `json:"port,omitempty" yaml:"port,omitempty"`
+	TLS         *bool             `json:"tls,omitempty" yaml:"tls,omitempty"`
+	Token       string            `json:"token,omitempty" yaml:"token,omitempty"`
+	Service     string            `json:"service,omitempty" yaml:"service,omitempty"`
+	ServicePath string            `mapstructure:"service-path" json:"service-path,omitempty" yaml:"service-path,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Auth        *Auth             `json:"auth,omitempty" yaml:"auth,omitempty"`
+
+	CAFile             string `mapstructure:"ca-file" json:"ca-file,omitempty" yaml:"ca-file,omitempty"`
+	CertFile           string `mapstructure:"cert-file" json:"cert-file,omitempty" yaml:"cert-file,omitempty"`
+	KeyFile            string `mapstructure:"key-file" json:"key-file,omitempty" yaml:"key-file,omitempty"`
+	InsecureSkipVerify bool   `mapstructure:"insecure-skip-verify" json:"insecure-skip-verify,omitempty" yaml:"insecure-skip-verify,omitempty"`
+	ServerName         string `mapstructure:"server-name" json:"server-name,omitempty" yaml:"server-name,omitempty"`
+}
+
+// Auth holds the settings used to acquire tokens from an identity
+// manager.
+type Auth struct {
+	Type         string `json:"type,omitempty" yaml:"type,omitempty"`
+	URL          string `json:"url,omitempty" yaml:"url,omitempty"`
+	Username     string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password     string `json:"password,omitempty" yaml:"password,omitempty"`
+	Domain       string `json:"domain,omitempty" yaml:"domain,omitempty"`
+	ClientID     string `mapstructure:"client-id" json:"client-id,omitempty" yaml:"client-id,omitempty"`
+	ClientSecret string `mapstructure:"client-secret" json:"client-secret,omitempty" yaml:"client-secret,omitempty"`
+}
+
+// context returns the context named name.
+func (c *Config) context(name string) (*Context, bool) {
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == name {
+			return &c.Contexts[i], true
+		}
+	}
+	return nil, false
 }
@@ -0,0 +1,141 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+)
+
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update Orion resources",
+	Long:  "Update Orion resources in place",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Help()
+			os.Exit(0)
+		}
+	},
+}
+
+// patchFields returns the top level fields of after whose value differs
+// from before, encoded as JSON.
+func patchFields(before, after interface{}) (map[string]json.RawMessage, error) {
+	b, err := rawFields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := rawFields(after)
+	if err != nil {
+		return nil, err
+	}
+	patch := map[string]json.RawMessage{}
+	for name, value := range a {
+		if !sameJSON(b[name], value) {
+			patch[name] = value
+		}
+	}
+	return patch, nil
+}
+
+// mergeFields merges the fields of src into dst the way JSON is decoded
+// into an existing value: objects are merged field by field, any other
+// value replaces the one in dst.
+func mergeFields(dst, src map[string]interface{}) {
+	for name, value := range src {
+		if from, ok := value.(map[string]interface{}); ok {
+			if to, ok := dst[name].(map[string]interface{}); ok {
+				mergeFields(to, from)
+				continue
+			}
+		}
+		dst[name] = value
+	}
+}
+
+func rawFields(v interface{}) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// changeTable lists the fields that differ between before and after, one
+// row per changed value, named by their dotted path.
+func changeTable(before, after interface{}) (*uitable.Table, error) {
+	b, err := toGeneric(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := toGeneric(after)
+	if err != nil {
+		return nil, err
+	}
+	from, to := map[string]string{}, map[string]string{}
+	flattenFields("", b, from)
+	flattenFields("", a, to)
+
+	names := []string{}
+	for name := range from {
+		if to[name] != from[name] {
+			names = append(names, name)
+		}
+	}
+	for name := range to {
+		if _, ok := from[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	table := uitable.New()
+	table.MaxColWidth = 50
+	table.AddRow("Field", "Before", "After")
+	for _, name := range names {
+		table.AddRow(name, from[name], to[name])
+	}
+	return table, nil
+}
+
+// flattenFields collects the values of v by their dotted path. Lists of
+// values are kept whole.
+func flattenFields(prefix string, v interface{}, fields map[string]string) {
+	object, ok := v.(map[string]interface{})
+	if !ok {
+		fields[prefix] = formatValue(v)
+		return
+	}
+	for name, value := range object {
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		flattenFields(name, value, fields)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(updateCmd)
+}
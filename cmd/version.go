@@ -16,9 +16,6 @@ limitations under the License.
 package cmd
 
 import (
-	"context"
-	"fmt"
-
 	"github.com/gosuri/uitable"
 	"github.com/spf13/cobra"
 	"github.com/YujiAzama/orionclient-go/orionclient"
@@ -30,32 +27,41 @@ var getVersionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Get version",
 	Long:  "Get version",
-	Run: func(cmd *cobra.Command, args []string) {
-		oc := orionclient.ClientConfig{Host: config.Host, Port: config.Port, TLS: config.TLS, Token: config.Token}
-		client, err := orionclient.NewClient(oc)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
 		if err != nil {
-			panic(err)
+			return err
 		}
-		version, err := client.GetVersion(context.Background())
+		version, err := client.GetVersion(cmd.Context())
 		if err != nil {
-			panic(err)
+			return err
 		}
 
-		table := uitable.New()
-		table.MaxColWidth = 80
-		table.Wrap = true
-		table.AddRow("Version", version.Orion.Version)
-		table.AddRow("Uptime", version.Orion.Uptime)
-		table.AddRow("GitHash", version.Orion.GitHash)
-		table.AddRow("CompileTime", version.Orion.CompileTime)
-		table.AddRow("CompiledBy", version.Orion.CompiledBy)
-		table.AddRow("CompiledIn", version.Orion.CompiledIn)
-		table.AddRow("ReleaseDate", version.Orion.ReleaseDate)
-		table.AddRow("Doc", version.Orion.Doc)
-		fmt.Println(table)
+		err = printResources(version, false, []string{version.Orion.Version}, func(wide bool) *uitable.Table {
+			return versionTable(version)
+		})
+		if err != nil {
+			return err
+		}
+		return nil
 	},
 }
 
+func versionTable(version *orionclient.Version) *uitable.Table {
+	table := uitable.New()
+	table.MaxColWidth = 80
+	table.Wrap = true
+	table.AddRow("Version", version.Orion.Version)
+	table.AddRow("Uptime", version.Orion.Uptime)
+	table.AddRow("GitHash", version.Orion.GitHash)
+	table.AddRow("CompileTime", version.Orion.CompileTime)
+	table.AddRow("CompiledBy", version.Orion.CompiledBy)
+	table.AddRow("CompiledIn", version.Orion.CompiledIn)
+	table.AddRow("ReleaseDate", version.Orion.ReleaseDate)
+	table.AddRow("Doc", version.Orion.Doc)
+	return table
+}
+
 func init() {
 	rootCmd.AddCommand(getVersionCmd)
 }
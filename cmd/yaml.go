@@ -0,0 +1,145 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// toYAML encodes v as YAML, keeping the field names and field order of
+// its JSON encoding.
+func toYAML(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	doc, err := decodeOrdered(dec)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
+
+// decodeOrdered decodes the next JSON value from dec, using yaml.MapSlice
+// for objects so that their key order is preserved.
+func decodeOrdered(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '{' {
+			m := yaml.MapSlice{}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := decodeOrdered(dec)
+				if err != nil {
+					return nil, err
+				}
+				m = append(m, yaml.MapItem{Key: key, Value: value})
+			}
+			_, err := dec.Token()
+			return m, err
+		}
+		s := []interface{}{}
+		for dec.More() {
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, value)
+		}
+		_, err := dec.Token()
+		return s, err
+	case json.Number:
+		if i, err := tok.Int64(); err == nil {
+			return i, nil
+		}
+		return tok.Float64()
+	default:
+		return tok, nil
+	}
+}
+
+// convertYAML turns the map[interface{}]interface{} values produced by the
+// yaml package into map[string]interface{} so they can be encoded as JSON.
+func convertYAML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for key, value := range v {
+			m[fmt.Sprint(key)] = convertYAML(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range v {
+			v[i] = convertYAML(value)
+		}
+	}
+	return v
+}
+
+// orderedFields decodes the JSON encoding of v like decodeOrdered, leaving
+// out the given fields, named by their dotted path.
+func orderedFields(v interface{}, drop []string) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	doc, err := decodeOrdered(dec)
+	if err != nil {
+		return nil, err
+	}
+	return dropOrdered(doc, drop), nil
+}
+
+// dropOrdered removes the fields at the given dotted paths from the
+// objects decoded by decodeOrdered.
+func dropOrdered(doc interface{}, drop []string) interface{} {
+	fields, ok := doc.(yaml.MapSlice)
+	if !ok || len(drop) == 0 {
+		return doc
+	}
+	kept := yaml.MapSlice{}
+	for _, field := range fields {
+		name := fmt.Sprint(field.Key)
+		if containsString(drop, name) {
+			continue
+		}
+		var nested []string
+		for _, path := range drop {
+			if strings.HasPrefix(path, name+".") {
+				nested = append(nested, strings.TrimPrefix(path, name+"."))
+			}
+		}
+		field.Value = dropOrdered(field.Value, nested)
+		kept = append(kept, field)
+	}
+	return kept
+}
@@ -4,7 +4,7 @@ go 1.14
 
 require (
 	github.com/YujiAzama/orionclient-go v0.0.0-20200907032800-d34a48fa2cec
-	github.com/fatih/color v1.9.0 // indirect
+	github.com/fatih/color v1.9.0
 	github.com/fsnotify/fsnotify v1.4.9 // indirect
 	github.com/gosuri/uitable v0.0.4
 	github.com/magiconair/properties v1.8.2 // indirect
@@ -21,4 +21,5 @@ require (
 	github.com/spf13/viper v1.7.1
 	golang.org/x/sys v0.0.0-20200905004654-be1d3432aa8f // indirect
 	gopkg.in/ini.v1 v1.61.0 // indirect
+	gopkg.in/yaml.v2 v2.3.0
 )
@@ -0,0 +1,142 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package orion
+
+import (
+	"context"
+	"net/http"
+)
+
+// Metrics maps the names of the metrics of /admin/metrics, such as
+// incomingTransactions or serviceTime, to their values.
+type Metrics map[string]float64
+
+// MetricsReport is the response of /admin/metrics: the metrics of every
+// service path of every service, and their sums.
+type MetricsReport struct {
+	Services map[string]ServiceMetrics `json:"services"`
+	Sum      struct {
+		Services map[string]Metrics `json:"services"`
+		Sum      Metrics            `json:"sum"`
+	} `json:"sum"`
+}
+
+// ServiceMetrics are the metrics of the service paths of a service.
+type ServiceMetrics struct {
+	Subservs map[string]Metrics `json:"subservs"`
+	Sum      Metrics            `json:"sum"`
+}
+
+// LogLevels are the log levels accepted by SetLogLevel.
+var LogLevels = []string{"NONE", "FATAL", "ERROR", "WARN", "INFO", "DEBUG"}
+
+// GetMetrics returns the metrics of Orion. With reset, Orion resets them
+// after building the response.
+func (c *Client) GetMetrics(ctx context.Context, reset bool) (*MetricsReport, error) {
+	queries := map[string]string{}
+	if reset {
+		queries["reset"] = "true"
+	}
+	req, err := c.newRequest(ctx, http.MethodGet, "/admin/metrics", queries, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics *MetricsReport
+	if _, err := c.doRequest(req, &metrics); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// GetStatistics returns the statistics of Orion. Their fields depend on
+// the version of Orion and on whether it runs with -statCounters,
+// -statSemWait, -statTiming and -statNotifQueue.
+func (c *Client) GetStatistics(ctx context.Context) (map[string]interface{}, error) {
+	return c.getAdminObject(ctx, "/statistics")
+}
+
+// ResetStatistics resets the statistics of Orion.
+func (c *Client) ResetStatistics(ctx context.Context) error {
+	return c.deleteAdminObject(ctx, "/statistics")
+}
+
+// GetCacheStatistics returns the statistics of the subscription cache of
+// Orion.
+func (c *Client) GetCacheStatistics(ctx context.Context) (map[string]interface{}, error) {
+	return c.getAdminObject(ctx, "/cache/statistics")
+}
+
+// ResetCacheStatistics resets the statistics of the subscription cache of
+// Orion.
+func (c *Client) ResetCacheStatistics(ctx context.Context) error {
+	return c.deleteAdminObject(ctx, "/cache/statistics")
+}
+
+// GetSemaphores returns the state of the semaphores of Orion by name.
+func (c *Client) GetSemaphores(ctx context.Context) (map[string]interface{}, error) {
+	return c.getAdminObject(ctx, "/admin/sem")
+}
+
+// GetLogLevel returns the log level of Orion.
+func (c *Client) GetLogLevel(ctx context.Context) (string, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/admin/log", nil, nil, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var log struct {
+		Level string `json:"level"`
+	}
+	if _, err := c.doRequest(req, &log); err != nil {
+		return "", err
+	}
+	return log.Level, nil
+}
+
+// SetLogLevel sets the log level of Orion to one of LogLevels.
+func (c *Client) SetLogLevel(ctx context.Context, level string) error {
+	req, err := c.newRequest(ctx, http.MethodPut, "/admin/log", map[string]string{"level": level}, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.doRequest(req, nil)
+	return err
+}
+
+func (c *Client) getAdminObject(ctx context.Context, path string) (map[string]interface{}, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	object := map[string]interface{}{}
+	if _, err := c.doRequest(req, &object); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+func (c *Client) deleteAdminObject(ctx context.Context, path string) error {
+	req, err := c.newRequest(ctx, http.MethodDelete, path, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.doRequest(req, nil)
+	return err
+}
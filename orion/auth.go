@@ -0,0 +1,326 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package orion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenSource acquires access tokens from an identity manager.
+type TokenSource interface {
+	// Token returns a new token and the time it expires.
+	Token(ctx context.Context) (string, time.Time, error)
+}
+
+// Keystone acquires X-Auth-Token tokens from OpenStack Keystone with the
+// password method, scoped to a domain, which is the Fiware service, and
+// optionally to a project, which is the Fiware service path.
+type Keystone struct {
+	URL      string
+	Username string
+	Password string
+	Domain   string
+	Project  string
+	Client   *http.Client
+}
+
+func (k *Keystone) Token(ctx context.Context) (string, time.Time, error) {
+	type name struct {
+		Name string `json:"name"`
+	}
+	type scoped struct {
+		Name   string `json:"name,omitempty"`
+		Domain name   `json:"domain"`
+	}
+	var body struct {
+		Auth struct {
+			Identity struct {
+				Methods  []string `json:"methods"`
+				Password struct {
+					User struct {
+						Name     string `json:"name"`
+						Password string `json:"password"`
+						Domain   name   `json:"domain"`
+					} `json:"user"`
+				} `json:"password"`
+			} `json:"identity"`
+			Scope map[string]interface{} `json:"scope,omitempty"`
+		} `json:"auth"`
+	}
+	body.Auth.Identity.Methods = []string{"password"}
+	body.Auth.Identity.Password.User.Name = k.Username
+	body.Auth.Identity.Password.User.Password = k.Password
+	body.Auth.Identity.Password.User.Domain.Name = k.Domain
+	if k.Project != "" {
+		body.Auth.Scope = map[string]interface{}{"project": scoped{Name: k.Project, Domain: name{k.Domain}}}
+	} else if k.Domain != "" {
+		body.Auth.Scope = map[string]interface{}{"domain": name{k.Domain}}
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(k.URL, "/")+"/v3/auth/tokens", bytes.NewReader(b))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var result struct {
+		Token struct {
+			ExpiresAt time.Time `json:"expires_at"`
+		} `json:"token"`
+	}
+	resp, err := doAuthRequest(k.Client, req.WithContext(ctx), &result)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("keystone: %w", err)
+	}
+	token := resp.Header.Get("X-Subject-Token")
+	if token == "" {
+		return "", time.Time{}, fmt.Errorf("keystone: no X-Subject-Token in the response")
+	}
+	return token, result.Token.ExpiresAt, nil
+}
+
+// Keyrock acquires OAuth2 access tokens from Keyrock, with the password
+// grant when Username is set and the client credentials grant otherwise.
+type Keyrock struct {
+	URL          string
+	ClientID     string
+	ClientSecret string
+	Username     string
+	Password     string
+	Client       *http.Client
+}
+
+func (k *Keyrock) Token(ctx context.Context) (string, time.Time, error) {
+	form := url.Values{}
+	if k.Username != "" {
+		form.Set("grant_type", "password")
+		form.Set("username", k.Username)
+		form.Set("password", k.Password)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(k.URL, "/")+"/oauth2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(k.ClientID, k.ClientSecret)
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if _, err := doAuthRequest(k.Client, req.WithContext(ctx), &result); err != nil {
+		return "", time.Time{}, fmt.Errorf("keyrock: %w", err)
+	}
+	if result.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("keyrock: no access_token in the response")
+	}
+	return result.AccessToken, time.Now().Add(time.Duration(result.ExpiresIn) * time.Second), nil
+}
+
+// doAuthRequest sends a request to an identity manager. Failures are
+// reported as an Error with status 401, whatever status the identity
+// manager answered, since they mean that orionctl cannot authenticate.
+func doAuthRequest(client *http.Client, req *http.Request, respBody interface{}) (*http.Response, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp, err
+	}
+	if resp.StatusCode < 200 || 300 <= resp.StatusCode {
+		return resp, &Error{StatusCode: http.StatusUnauthorized, Code: http.StatusText(resp.StatusCode), Description: authErrorDescription(b)}
+	}
+	if err := json.Unmarshal(b, respBody); err != nil && len(b) > 0 {
+		return resp, err
+	}
+	return resp, nil
+}
+
+// authErrorDescription extracts the message of a Keystone or OAuth2 error
+// body.
+func authErrorDescription(b []byte) string {
+	var body struct {
+		Error            json.RawMessage `json:"error"`
+		ErrorDescription string          `json:"error_description"`
+	}
+	if err := json.Unmarshal(b, &body); err != nil {
+		return strings.TrimSpace(string(b))
+	}
+	if body.ErrorDescription != "" {
+		return body.ErrorDescription
+	}
+	var keystone struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body.Error, &keystone); err == nil && keystone.Message != "" {
+		return keystone.Message
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// TokenCache keeps the token of a TokenSource until it expires, in memory
+// and in File when it is set, so that it is shared between invocations.
+// Key identifies the credentials; a cached token acquired with other
+// credentials is not used.
+type TokenCache struct {
+	Source TokenSource
+	File   string
+	Key    string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+type cachedToken struct {
+	Key     string    `json:"key"`
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
+
+// expiryMargin is how long before it expires a token is renewed.
+const expiryMargin = time.Minute
+
+// Token returns the cached token, acquiring a new one when there is none
+// or it is about to expire.
+func (c *TokenCache) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.valid() {
+		return c.token, nil
+	}
+	if c.File != "" {
+		var cached cachedToken
+		if b, err := ioutil.ReadFile(c.File); err == nil && json.Unmarshal(b, &cached) == nil && cached.Key == c.Key {
+			c.token, c.expires = cached.Token, cached.Expires
+			if c.valid() {
+				return c.token, nil
+			}
+		}
+	}
+
+	token, expires, err := c.Source.Token(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token, c.expires = token, expires
+	if c.File != "" {
+		b, err := json.Marshal(cachedToken{Key: c.Key, Token: token, Expires: expires})
+		if err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(filepath.Dir(c.File), 0700); err != nil {
+			return "", err
+		}
+		if err := ioutil.WriteFile(c.File, b, 0600); err != nil {
+			return "", err
+		}
+	}
+	return token, nil
+}
+
+// Invalidate drops token if it is the cached one, so that the next call to
+// Token acquires a new one.
+func (c *TokenCache) Invalidate(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != token {
+		return
+	}
+	c.token, c.expires = "", time.Time{}
+	if c.File != "" {
+		os.Remove(c.File)
+	}
+}
+
+func (c *TokenCache) valid() bool {
+	return c.token != "" && (c.expires.IsZero() || time.Now().Add(expiryMargin).Before(c.expires))
+}
+
+// AuthTransport sets the X-Auth-Token header of every request from Tokens.
+// When Orion answers 401, the token is renewed and the request is sent
+// once more.
+type AuthTransport struct {
+	Base   http.RoundTripper
+	Tokens *TokenCache
+}
+
+func (t *AuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.Tokens.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base().RoundTrip(withToken(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	t.Tokens.Invalidate(token)
+	if token, err = t.Tokens.Token(req.Context()); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	retry := withToken(req, token)
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+	resp.Body.Close()
+	return t.base().RoundTrip(retry)
+}
+
+func (t *AuthTransport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+// withToken returns a copy of req with the X-Auth-Token header set, as
+// RoundTrippers must not modify their request.
+func withToken(req *http.Request, token string) *http.Request {
+	r := req.Clone(req.Context())
+	r.Header.Set("X-Auth-Token", token)
+	return r
+}
@@ -0,0 +1,47 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package orion
+
+import (
+	"context"
+	"net/http"
+)
+
+// Batch update actions.
+const (
+	ActionAppend       = "append"
+	ActionAppendStrict = "appendStrict"
+	ActionUpdate       = "update"
+	ActionDelete       = "delete"
+	ActionReplace      = "replace"
+)
+
+type batchUpdate struct {
+	ActionType string    `json:"actionType"`
+	Entities   []*Entity `json:"entities"`
+}
+
+// BatchUpdate applies actionType to every entity in a single request.
+func (c *Client) BatchUpdate(ctx context.Context, actionType string, entities []*Entity, fs string, fsp string) error {
+	body := batchUpdate{ActionType: actionType, Entities: entities}
+	req, err := c.newRequest(ctx, http.MethodPost, "/v2/op/update", nil, serviceHeaders(fs, fsp), body)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.doRequest(req, nil)
+	return err
+}
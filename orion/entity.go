@@ -0,0 +1,121 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package orion
+
+import (
+	"context"
+	"net/http"
+	"path"
+)
+
+// GetEntities returns every entity matching query, following pagination.
+func (c *Client) GetEntities(ctx context.Context, query EntityQuery, fs string, fsp string) ([]*Entity, error) {
+	var entities []*Entity
+	_, err := Paginate(0, 0, func(page Page) (int, int, error) {
+		items, total, err := c.GetEntitiesPage(ctx, query, page, fs, fsp)
+		entities = append(entities, items...)
+		return len(items), total, err
+	})
+	return entities, err
+}
+
+// GetEntitiesPage returns one page of entities matching query and the total
+// number of matching entities.
+func (c *Client) GetEntitiesPage(ctx context.Context, query EntityQuery, page Page, fs string, fsp string) ([]*Entity, int, error) {
+	if err := query.Validate(); err != nil {
+		return nil, 0, err
+	}
+	queries := query.queries()
+	for k, v := range page.queries() {
+		queries[k] = v
+	}
+	req, err := c.newRequest(ctx, http.MethodGet, "/v2/entities", queries, serviceHeaders(fs, fsp), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var entities []*Entity
+	resp, err := c.doRequest(req, &entities)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entities, totalCount(resp, len(entities)), nil
+}
+
+func (c *Client) GetEntity(ctx context.Context, id string, entityType string, fs string, fsp string) (*Entity, error) {
+	relativePath := path.Join("/v2/entities", id)
+	queries := map[string]string{
+		"type": entityType,
+	}
+	req, err := c.newRequest(ctx, http.MethodGet, relativePath, queries, serviceHeaders(fs, fsp), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var entity *Entity
+	if _, err := c.doRequest(req, &entity); err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+func (c *Client) CreateEntity(ctx context.Context, entity Entity, fs string, fsp string) (string, error) {
+	req, err := c.newRequest(ctx, http.MethodPost, "/v2/entities", nil, serviceHeaders(fs, fsp), entity)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.doRequest(req, nil)
+	if err != nil {
+		return "", err
+	}
+	if entityId := locationId(resp); entityId != "" {
+		return entityId, nil
+	}
+	return entity.Id, nil
+}
+
+func (c *Client) DeleteEntity(ctx context.Context, id string, entityType string, fs string, fsp string) error {
+	relativePath := path.Join("/v2/entities", id)
+	queries := map[string]string{
+		"type": entityType,
+	}
+	req, err := c.newRequest(ctx, http.MethodDelete, relativePath, queries, serviceHeaders(fs, fsp), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.doRequest(req, nil)
+	return err
+}
+
+// ReplaceEntityAttrs replaces every attribute of an existing entity with
+// the attributes of entity.
+func (c *Client) ReplaceEntityAttrs(ctx context.Context, entity Entity, fs string, fsp string) error {
+	relativePath := path.Join("/v2/entities", entity.Id, "attrs")
+	queries := map[string]string{
+		"type": entity.Type,
+	}
+	attrs := Entity{Attrs: entity.Attrs}
+	req, err := c.newRequest(ctx, http.MethodPut, relativePath, queries, serviceHeaders(fs, fsp), attrs)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.doRequest(req, nil)
+	return err
+}
@@ -0,0 +1,62 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package orion
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Error is an NGSIv2 error response returned by Orion.
+type Error struct {
+	StatusCode  int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"description"`
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+// newError builds an Error from a non 2xx response body, falling back to
+// the HTTP status text when the body is not an NGSIv2 error.
+func newError(statusCode int, body []byte) *Error {
+	e := &Error{}
+	if err := json.Unmarshal(body, e); err != nil || e.Code == "" {
+		e = &Error{Code: http.StatusText(statusCode)}
+		if len(body) > 0 && len(body) < 512 {
+			e.Description = string(body)
+		}
+	}
+	e.StatusCode = statusCode
+	return e
+}
+
+// QueryError reports a query rejected locally, before sending the request.
+type QueryError struct {
+	Err error
+}
+
+func (e *QueryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
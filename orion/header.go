@@ -0,0 +1,52 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package orion
+
+import (
+	"net/http"
+)
+
+// HeaderTransport adds Header to every request that does not set the same
+// headers itself, such as the API keys required by a gateway in front of
+// Orion. A Host header replaces the host name sent to the server.
+type HeaderTransport struct {
+	Base   http.RoundTripper
+	Header http.Header
+}
+
+func (t *HeaderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	if r.Header == nil {
+		r.Header = http.Header{}
+	}
+	for name, values := range t.Header {
+		if name == "Host" {
+			r.Host = values[0]
+			continue
+		}
+		if _, ok := r.Header[name]; !ok {
+			r.Header[name] = values
+		}
+	}
+	return t.base().RoundTrip(r)
+}
+
+func (t *HeaderTransport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
@@ -0,0 +1,128 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package orion extends orionclient with the NGSIv2 endpoints orionctl
+// needs but orionclient does not provide yet.
+package orion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+
+	"github.com/YujiAzama/orionclient-go/orionclient"
+)
+
+// Client is an orionclient.Client with additional NGSIv2 operations.
+type Client struct {
+	*orionclient.Client
+}
+
+func NewClient(config orionclient.ClientConfig) (*Client, error) {
+	client, err := orionclient.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{Client: client}, nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, relativePath string, queries, headers map[string]string, reqBody interface{}) (*http.Request, error) {
+	reqURL := *c.BaseURL
+	reqURL.Path = path.Join(reqURL.Path, relativePath)
+
+	if queries != nil {
+		q := reqURL.Query()
+		for k, v := range queries {
+			if v != "" {
+				q.Add(k, v)
+			}
+		}
+		reqURL.RawQuery = q.Encode()
+	}
+
+	var body io.Reader
+	if reqBody != nil {
+		jsonBytes, err := json.Marshal(reqBody)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(jsonBytes)
+	}
+
+	req, err := http.NewRequest(method, reqURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	for k, v := range headers {
+		if v != "" {
+			req.Header.Set(k, v)
+		}
+	}
+
+	return req.WithContext(ctx), nil
+}
+
+func (c *Client) doRequest(req *http.Request, respBody interface{}) (*http.Response, error) {
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode < 200 || 300 <= resp.StatusCode {
+		return resp, newError(resp.StatusCode, bodyBytes)
+	}
+
+	if respBody != nil && len(bodyBytes) > 0 {
+		if err := json.Unmarshal(bodyBytes, respBody); err != nil {
+			return resp, err
+		}
+	}
+
+	return resp, nil
+}
+
+func serviceHeaders(fs, fsp string) map[string]string {
+	return map[string]string{
+		"Fiware-Service":     fs,
+		"Fiware-ServicePath": fsp,
+	}
+}
+
+// locationId returns the ID of a created resource from the Location header.
+func locationId(resp *http.Response) string {
+	locationUrl, err := resp.Location()
+	if err != nil {
+		return ""
+	}
+	return path.Base(locationUrl.Path)
+}
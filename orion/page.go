@@ -0,0 +1,70 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package orion
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// MaxPageSize is the largest limit Orion accepts on list requests.
+const MaxPageSize = 1000
+
+// Page selects a window of a list request.
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+func (p Page) queries() map[string]string {
+	return map[string]string{
+		"limit":   strconv.Itoa(p.Limit),
+		"offset":  strconv.Itoa(p.Offset),
+		"options": "count",
+	}
+}
+
+// Paginate calls fetch for consecutive pages starting at offset until limit
+// items have been fetched or the list is exhausted. A limit of 0 fetches
+// every page. fetch returns the number of items on the page and the total
+// count reported by Orion, which Paginate returns.
+func Paginate(limit int, offset int, fetch func(page Page) (int, int, error)) (int, error) {
+	total := 0
+	fetched := 0
+	for {
+		page := Page{Limit: MaxPageSize, Offset: offset + fetched}
+		if limit > 0 && limit-fetched < MaxPageSize {
+			page.Limit = limit - fetched
+		}
+		n, count, err := fetch(page)
+		if err != nil {
+			return total, err
+		}
+		total = count
+		fetched += n
+		if n == 0 || n < page.Limit || page.Offset+n >= total || (limit > 0 && fetched >= limit) {
+			return total, nil
+		}
+	}
+}
+
+func totalCount(resp *http.Response, n int) int {
+	total, err := strconv.Atoi(resp.Header.Get("Fiware-Total-Count"))
+	if err != nil {
+		return n
+	}
+	return total
+}
@@ -0,0 +1,326 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package orion
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EntityQuery holds the NGSIv2 query parameters of GET /v2/entities.
+type EntityQuery struct {
+	Type      string
+	IdPattern string
+	Q         string
+	MQ        string
+	Attrs     string
+	Metadata  string
+	OrderBy   string
+	Georel    string
+	Geometry  string
+	Coords    string
+}
+
+// Validate checks the query parameters locally. It returns a *QueryError
+// describing the first problem found.
+func (q EntityQuery) Validate() error {
+	if err := q.validate(); err != nil {
+		return &QueryError{Err: err}
+	}
+	return nil
+}
+
+func (q EntityQuery) validate() error {
+	if q.Q != "" {
+		if err := ValidateQuery(q.Q); err != nil {
+			return err
+		}
+	}
+	if q.MQ != "" {
+		if err := ValidateMetadataQuery(q.MQ); err != nil {
+			return err
+		}
+	}
+	if q.OrderBy != "" {
+		for _, attr := range strings.Split(q.OrderBy, ",") {
+			if strings.TrimPrefix(attr, "!") == "" {
+				return fmt.Errorf("invalid orderBy %q: empty attribute name", q.OrderBy)
+			}
+		}
+	}
+	if q.Georel != "" || q.Geometry != "" || q.Coords != "" {
+		if err := validateGeoQuery(q.Georel, q.Geometry, q.Coords); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q EntityQuery) queries() map[string]string {
+	return map[string]string{
+		"type":      q.Type,
+		"idPattern": q.IdPattern,
+		"q":         q.Q,
+		"mq":        q.MQ,
+		"attrs":     q.Attrs,
+		"metadata":  q.Metadata,
+		"orderBy":   q.OrderBy,
+		"georel":    q.Georel,
+		"geometry":  q.Geometry,
+		"coords":    q.Coords,
+	}
+}
+
+// ValidateQuery checks that q is a well formed expression of the NGSIv2
+// Simple Query Language, e.g. "temperature>20;humidity==50..70".
+func ValidateQuery(q string) error {
+	return validateQuery("q", q, false)
+}
+
+// ValidateMetadataQuery checks an mq expression, where every statement
+// refers to attribute metadata as "attr.metadata".
+func ValidateMetadataQuery(mq string) error {
+	return validateQuery("mq", mq, true)
+}
+
+// NearGeorel builds the georel of a "near" query from distances in meters.
+// A zero distance is left out.
+func NearGeorel(maxDistance, minDistance float64) string {
+	georel := "near"
+	if maxDistance > 0 {
+		georel += ";maxDistance:" + strconv.FormatFloat(maxDistance, 'f', -1, 64)
+	}
+	if minDistance > 0 {
+		georel += ";minDistance:" + strconv.FormatFloat(minDistance, 'f', -1, 64)
+	}
+	return georel
+}
+
+func validateGeoQuery(georel, geometry, coords string) error {
+	if georel == "" || geometry == "" || coords == "" {
+		return fmt.Errorf("georel, geometry and coords must be used together")
+	}
+
+	modifiers := strings.Split(georel, ";")
+	switch modifiers[0] {
+	case "near":
+		if len(modifiers) == 1 {
+			return fmt.Errorf("invalid georel %q: near requires maxDistance or minDistance", georel)
+		}
+		for _, modifier := range modifiers[1:] {
+			kv := strings.SplitN(modifier, ":", 2)
+			if len(kv) != 2 || (kv[0] != "maxDistance" && kv[0] != "minDistance") {
+				return fmt.Errorf("invalid georel %q: unknown modifier %q", georel, modifier)
+			}
+			if d, err := strconv.ParseFloat(kv[1], 64); err != nil || d < 0 {
+				return fmt.Errorf("invalid georel %q: %s must be a positive number", georel, kv[0])
+			}
+		}
+		if geometry != "point" {
+			return fmt.Errorf("georel near requires geometry point")
+		}
+	case "coveredBy", "intersects", "equals", "disjoint":
+		if len(modifiers) > 1 {
+			return fmt.Errorf("invalid georel %q: %s takes no modifiers", georel, modifiers[0])
+		}
+	default:
+		return fmt.Errorf("invalid georel %q: expected near, coveredBy, intersects, equals or disjoint", georel)
+	}
+
+	points := strings.Split(coords, ";")
+	for _, point := range points {
+		latlon := strings.Split(point, ",")
+		if len(latlon) != 2 {
+			return fmt.Errorf("invalid coords %q: expected lat,lon pairs separated by ;", coords)
+		}
+		lat, err := strconv.ParseFloat(strings.TrimSpace(latlon[0]), 64)
+		if err != nil || lat < -90 || lat > 90 {
+			return fmt.Errorf("invalid coords %q: latitude %q out of range", coords, latlon[0])
+		}
+		lon, err := strconv.ParseFloat(strings.TrimSpace(latlon[1]), 64)
+		if err != nil || lon < -180 || lon > 180 {
+			return fmt.Errorf("invalid coords %q: longitude %q out of range", coords, latlon[1])
+		}
+	}
+
+	switch geometry {
+	case "point":
+		if len(points) != 1 {
+			return fmt.Errorf("geometry point takes exactly one coordinate")
+		}
+	case "line":
+		if len(points) < 2 {
+			return fmt.Errorf("geometry line takes at least two coordinates")
+		}
+	case "box":
+		if len(points) != 2 {
+			return fmt.Errorf("geometry box takes exactly two coordinates")
+		}
+	case "polygon":
+		if len(points) < 4 || points[0] != points[len(points)-1] {
+			return fmt.Errorf("geometry polygon takes at least four coordinates and must be closed")
+		}
+	default:
+		return fmt.Errorf("invalid geometry %q: expected point, line, polygon or box", geometry)
+	}
+	return nil
+}
+
+var queryOperators = []string{"==", "!=", ">=", "<=", "~=", ">", "<", ":"}
+
+func validateQuery(param string, expr string, metadata bool) error {
+	statements, err := splitUnquoted(expr, ';')
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %v", param, expr, err)
+	}
+	for _, statement := range statements {
+		if err := validateStatement(statement, metadata); err != nil {
+			return fmt.Errorf("invalid %s %q: statement %q %v", param, expr, statement, err)
+		}
+	}
+	return nil
+}
+
+func validateStatement(statement string, metadata bool) error {
+	if strings.TrimSpace(statement) == "" {
+		return fmt.Errorf("is empty")
+	}
+
+	pos := indexUnquoted(statement, "=!<>~:")
+	if pos < 0 || (pos == 0 && statement[0] == '!' && indexUnquoted(statement[1:], "=!<>~:") < 0) {
+		return validatePath(strings.TrimPrefix(statement, "!"), metadata)
+	}
+
+	var op string
+	for _, candidate := range queryOperators {
+		if strings.HasPrefix(statement[pos:], candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" && statement[pos] == '=' {
+		return fmt.Errorf("has unknown operator \"=\", did you mean \"==\"?")
+	}
+	if op == "" {
+		return fmt.Errorf("has unknown operator %q", statement[pos:pos+1])
+	}
+	if err := validatePath(statement[:pos], metadata); err != nil {
+		return err
+	}
+
+	value := statement[pos+len(op):]
+	if value == "" {
+		return fmt.Errorf("is missing a value after %q", op)
+	}
+	if strings.ContainsAny(value[:1], "=!<>~:") {
+		return fmt.Errorf("has unexpected %q after operator %q", value[:1], op)
+	}
+
+	items, err := splitUnquoted(value, ',')
+	if err != nil {
+		return err
+	}
+	isList := len(items) > 1
+	isRange := indexUnquotedString(value, "..") >= 0
+	switch {
+	case isList && isRange:
+		return fmt.Errorf("cannot combine a list and a range")
+	case (isList || isRange) && op != "==" && op != "!=" && op != ":":
+		return fmt.Errorf("can only use lists and ranges with == or !=")
+	case op == "~=" && strings.HasPrefix(value, "'") != strings.HasSuffix(value, "'"):
+		return fmt.Errorf("has an unterminated pattern")
+	}
+	if isRange {
+		bounds := strings.Split(value, "..")
+		if len(bounds) != 2 || bounds[0] == "" || bounds[1] == "" {
+			return fmt.Errorf("has an invalid range %q, expected min..max", value)
+		}
+	}
+	for _, item := range items {
+		if item == "" {
+			return fmt.Errorf("has an empty element in list %q", value)
+		}
+	}
+	return nil
+}
+
+func validatePath(path string, metadata bool) error {
+	if path == "" {
+		return fmt.Errorf("is missing an attribute name")
+	}
+	segments, err := splitUnquoted(path, '.')
+	if err != nil {
+		return err
+	}
+	if metadata && len(segments) < 2 {
+		return fmt.Errorf("must refer to metadata as attribute.metadata")
+	}
+	for _, segment := range segments {
+		name := strings.Trim(segment, "'")
+		if name == "" {
+			return fmt.Errorf("has an empty name in %q", path)
+		}
+		if strings.ContainsAny(name, " \t<>\"'=;()") {
+			return fmt.Errorf("has invalid characters in name %q", name)
+		}
+	}
+	return nil
+}
+
+// splitUnquoted splits s on sep, ignoring separators inside single quotes.
+func splitUnquoted(s string, sep byte) ([]string, error) {
+	var parts []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\'':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("has an unterminated quote")
+	}
+	return append(parts, s[start:]), nil
+}
+
+func indexUnquoted(s string, chars string) int {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\'' {
+			quoted = !quoted
+		} else if !quoted && strings.IndexByte(chars, s[i]) >= 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+func indexUnquotedString(s string, substr string) int {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\'' {
+			quoted = !quoted
+		} else if !quoted && strings.HasPrefix(s[i:], substr) {
+			return i
+		}
+	}
+	return -1
+}
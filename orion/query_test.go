@@ -0,0 +1,214 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package orion
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateQuery(t *testing.T) {
+	tests := []struct {
+		q     string
+		valid bool
+	}{
+		// Unary statements
+		{"temperature", true},
+		{"!temperature", true},
+		{"temperature;!humidity", true},
+
+		// Binary operators
+		{"temperature==20", true},
+		{"temperature!=20", true},
+		{"temperature>20", true},
+		{"temperature<20", true},
+		{"temperature>=20", true},
+		{"temperature<=20", true},
+		{"name~=^Room", true},
+		{"name~='^Room;[0-9]'", true},
+		{"name:'Room 1'", true},
+		{"temperature>20;humidity<80", true},
+
+		// Lists and ranges
+		{"color==black,white", true},
+		{"color!=black,white", true},
+		{"temperature==10..20", true},
+		{"temperature!=10..20", true},
+		{"date==2020-01-01T00:00:00Z..2020-12-31T00:00:00Z", true},
+		{"temperature==10..", false},
+		{"temperature==..20", false},
+		{"temperature==1..2..3", false},
+		{"temperature>10..20", false},
+		{"color>black,white", false},
+		{"color==black,", false},
+		{"color==a,b..c", false},
+
+		// Quoted values and names
+		{"name=='a;b'", true},
+		{"name=='a,b,c'", true},
+		{"name=='1..2'", true},
+		{"name=='a==b'", true},
+		{"address.'street.name'==Main", true},
+		{"name=='unterminated", false},
+		{"name~='^Room", false},
+
+		// Paths
+		{"address.city==Berlin", true},
+		{"==20", false},
+		{"address..city==Berlin", false},
+		{"temp erature==20", false},
+		{"temp(1)==20", false},
+
+		// Malformed statements and operators
+		{"", false},
+		{"temperature==20;", false},
+		{";temperature==20", false},
+		{"temperature=20", false},
+		{"temperature==", false},
+		{"temperature===20", false},
+		{"temperature>>20", false},
+		{"temperature=>20", false},
+	}
+	for _, test := range tests {
+		err := ValidateQuery(test.q)
+		if test.valid && err != nil {
+			t.Errorf("ValidateQuery(%q) = %v, want nil", test.q, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("ValidateQuery(%q) = nil, want an error", test.q)
+		}
+	}
+}
+
+func TestValidateMetadataQuery(t *testing.T) {
+	tests := []struct {
+		mq    string
+		valid bool
+	}{
+		{"temperature.accuracy", true},
+		{"!temperature.accuracy", true},
+		{"temperature.accuracy<0.5", true},
+		{"temperature.accuracy==0.1..0.5", true},
+		{"temperature.unit=='C;F'", true},
+		{"temperature.accuracy<0.5;humidity.accuracy<1", true},
+		{"temperature", false},
+		{"temperature<0.5", false},
+		{"temperature.<0.5", false},
+		{"temperature.accuracy=0.5", false},
+	}
+	for _, test := range tests {
+		err := ValidateMetadataQuery(test.mq)
+		if test.valid && err != nil {
+			t.Errorf("ValidateMetadataQuery(%q) = %v, want nil", test.mq, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("ValidateMetadataQuery(%q) = nil, want an error", test.mq)
+		}
+	}
+}
+
+func TestEntityQueryValidateGeo(t *testing.T) {
+	tests := []struct {
+		georel, geometry, coords string
+		valid                    bool
+	}{
+		{"near;maxDistance:1000", "point", "52.52,13.35", true},
+		{"near;minDistance:10", "point", "52.52,13.35", true},
+		{"near;maxDistance:1000;minDistance:10", "point", "52.52,13.35", true},
+		{"near", "point", "52.52,13.35", false},
+		{"near;maxDistance:-1", "point", "52.52,13.35", false},
+		{"near;maxDistance:far", "point", "52.52,13.35", false},
+		{"near;radius:1000", "point", "52.52,13.35", false},
+		{"near;maxDistance:1000", "box", "52.52,13.35;52.53,13.36", false},
+
+		{"coveredBy", "polygon", "52.5,13.3;52.6,13.3;52.6,13.4;52.5,13.3", true},
+		{"coveredBy", "polygon", "52.5,13.3;52.6,13.3;52.6,13.4", false},
+		{"coveredBy", "polygon", "52.5,13.3;52.6,13.3;52.6,13.4;52.5,13.4", false},
+		{"intersects", "line", "52.5,13.3;52.6,13.4", true},
+		{"intersects", "line", "52.5,13.3", false},
+		{"equals", "point", "52.5,13.3", true},
+		{"equals", "point", "52.5,13.3;52.6,13.4", false},
+		{"disjoint", "box", "52.5,13.3;52.6,13.4", true},
+		{"disjoint", "box", "52.5,13.3", false},
+		{"coveredBy;maxDistance:10", "box", "52.5,13.3;52.6,13.4", false},
+		{"within", "box", "52.5,13.3;52.6,13.4", false},
+		{"intersects", "circle", "52.5,13.3", false},
+
+		{"equals", "point", "91,13.3", false},
+		{"equals", "point", "52.5,181", false},
+		{"equals", "point", "52.5", false},
+		{"equals", "point", "north,east", false},
+
+		{"equals", "point", "", false},
+		{"equals", "", "52.5,13.3", false},
+		{"", "point", "52.5,13.3", false},
+	}
+	for _, test := range tests {
+		q := EntityQuery{Georel: test.georel, Geometry: test.geometry, Coords: test.coords}
+		err := q.Validate()
+		if test.valid && err != nil {
+			t.Errorf("georel=%q geometry=%q coords=%q: got %v, want nil", test.georel, test.geometry, test.coords, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("georel=%q geometry=%q coords=%q: got nil, want an error", test.georel, test.geometry, test.coords)
+		}
+	}
+}
+
+func TestEntityQueryValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		query EntityQuery
+		valid bool
+	}{
+		{"empty", EntityQuery{}, true},
+		{"q and mq", EntityQuery{Q: "temperature>20", MQ: "temperature.accuracy<1"}, true},
+		{"invalid q", EntityQuery{Q: "temperature=20"}, false},
+		{"invalid mq", EntityQuery{MQ: "temperature<1"}, false},
+		{"orderBy", EntityQuery{OrderBy: "temperature,!humidity"}, true},
+		{"orderBy with empty attribute", EntityQuery{OrderBy: "temperature,"}, false},
+		{"orderBy with empty descending attribute", EntityQuery{OrderBy: "!"}, false},
+	}
+	for _, test := range tests {
+		err := test.query.Validate()
+		if test.valid && err != nil {
+			t.Errorf("%s: got %v, want nil", test.name, err)
+		}
+		if !test.valid {
+			var queryErr *QueryError
+			if !errors.As(err, &queryErr) {
+				t.Errorf("%s: got %v, want a *QueryError", test.name, err)
+			}
+		}
+	}
+}
+
+func TestNearGeorel(t *testing.T) {
+	tests := []struct {
+		max, min float64
+		want     string
+	}{
+		{1000, 0, "near;maxDistance:1000"},
+		{0, 10, "near;minDistance:10"},
+		{1000, 10.5, "near;maxDistance:1000;minDistance:10.5"},
+		{0, 0, "near"},
+	}
+	for _, test := range tests {
+		if got := NearGeorel(test.max, test.min); got != test.want {
+			t.Errorf("NearGeorel(%v, %v) = %q, want %q", test.max, test.min, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package orion
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// getRawList returns every item of the list at relativePath as the JSON
+// Orion returns, following pagination.
+func (c *Client) getRawList(ctx context.Context, relativePath string, fs string, fsp string) ([]json.RawMessage, error) {
+	var items []json.RawMessage
+	_, err := Paginate(0, 0, func(page Page) (int, int, error) {
+		pageItems, total, err := c.getRawPage(ctx, relativePath, page, fs, fsp)
+		items = append(items, pageItems...)
+		return len(pageItems), total, err
+	})
+	return items, err
+}
+
+// getRawPage returns one page of the list at relativePath as the JSON Orion
+// returns and the total number of items in the list.
+func (c *Client) getRawPage(ctx context.Context, relativePath string, page Page, fs string, fsp string) ([]json.RawMessage, int, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, relativePath, page.queries(), serviceHeaders(fs, fsp), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var items []json.RawMessage
+	resp, err := c.doRequest(req, &items)
+	if err != nil {
+		return nil, 0, err
+	}
+	return items, totalCount(resp, len(items)), nil
+}
+
+// getRaw returns the resource at relativePath as the JSON Orion returns.
+func (c *Client) getRaw(ctx context.Context, relativePath string, fs string, fsp string) (json.RawMessage, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, relativePath, nil, serviceHeaders(fs, fsp), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var item json.RawMessage
+	if _, err := c.doRequest(req, &item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
@@ -0,0 +1,110 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package orion
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+
+	"github.com/YujiAzama/orionclient-go/orionclient"
+)
+
+// GetRegistrations returns every registration, following pagination.
+func (c *Client) GetRegistrations(ctx context.Context, fs string, fsp string) ([]*orionclient.Registration, error) {
+	var registrations []*orionclient.Registration
+	_, err := Paginate(0, 0, func(page Page) (int, int, error) {
+		items, total, err := c.GetRegistrationsPage(ctx, page, fs, fsp)
+		registrations = append(registrations, items...)
+		return len(items), total, err
+	})
+	return registrations, err
+}
+
+// GetRegistrationsPage returns one page of registrations and the total
+// number of registrations.
+func (c *Client) GetRegistrationsPage(ctx context.Context, page Page, fs string, fsp string) ([]*orionclient.Registration, int, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/v2/registrations", page.queries(), serviceHeaders(fs, fsp), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var registrations []*orionclient.Registration
+	resp, err := c.doRequest(req, &registrations)
+	if err != nil {
+		return nil, 0, err
+	}
+	return registrations, totalCount(resp, len(registrations)), nil
+}
+
+func (c *Client) GetRegistration(ctx context.Context, id string, fs string, fsp string) (*orionclient.Registration, error) {
+	relativePath := path.Join("/v2/registrations", id)
+	req, err := c.newRequest(ctx, http.MethodGet, relativePath, nil, serviceHeaders(fs, fsp), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var registration *orionclient.Registration
+	if _, err := c.doRequest(req, &registration); err != nil {
+		return nil, err
+	}
+	return registration, nil
+}
+
+// GetRawRegistrations returns every registration as the JSON Orion returns, keeping
+// the fields orionclient.Registration does not model.
+func (c *Client) GetRawRegistrations(ctx context.Context, fs string, fsp string) ([]json.RawMessage, error) {
+	return c.getRawList(ctx, "/v2/registrations", fs, fsp)
+}
+
+// GetRawRegistrationsPage returns one page of registrations as the JSON Orion returns and
+// the total number of registrations.
+func (c *Client) GetRawRegistrationsPage(ctx context.Context, page Page, fs string, fsp string) ([]json.RawMessage, int, error) {
+	return c.getRawPage(ctx, "/v2/registrations", page, fs, fsp)
+}
+
+// GetRawRegistration returns the registration with the given ID as the JSON Orion
+// returns.
+func (c *Client) GetRawRegistration(ctx context.Context, id string, fs string, fsp string) (json.RawMessage, error) {
+	return c.getRaw(ctx, path.Join("/v2/registrations", id), fs, fsp)
+}
+
+// CreateRegistration creates registration, an orionclient.Registration or any value
+// encoding a registration as JSON, and returns its ID.
+func (c *Client) CreateRegistration(ctx context.Context, registration interface{}, fs string, fsp string) (string, error) {
+	req, err := c.newRequest(ctx, http.MethodPost, "/v2/registrations", nil, serviceHeaders(fs, fsp), registration)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.doRequest(req, nil)
+	if err != nil {
+		return "", err
+	}
+	return locationId(resp), nil
+}
+
+func (c *Client) DeleteRegistration(ctx context.Context, id string, fs string, fsp string) error {
+	relativePath := path.Join("/v2/registrations", id)
+	req, err := c.newRequest(ctx, http.MethodDelete, relativePath, nil, serviceHeaders(fs, fsp), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.doRequest(req, nil)
+	return err
+}
@@ -0,0 +1,119 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package orion
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	minBackoff = 500 * time.Millisecond
+	maxBackoff = 30 * time.Second
+)
+
+// RetryTransport sends idempotent requests (GET, DELETE and PATCH) up to
+// Retries more times when they fail with a network error or Orion or its
+// proxy answers 429, 502, 503 or 504. Attempts are spaced by an
+// exponential backoff with jitter, or by the Retry-After header when there
+// is one.
+type RetryTransport struct {
+	Base    http.RoundTripper
+	Retries int
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !idempotent(req.Method) || (req.Body != nil && req.GetBody == nil) {
+		return t.base().RoundTrip(req)
+	}
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 && req.GetBody != nil {
+			r = req.Clone(req.Context())
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+		resp, err := t.base().RoundTrip(r)
+		if attempt >= t.Retries || !retryable(req.Context(), resp, err) {
+			return resp, err
+		}
+
+		wait := backoff(attempt)
+		if resp != nil {
+			if after, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && after >= 0 {
+				wait = time.Duration(after) * time.Second
+				if wait > maxBackoff {
+					wait = maxBackoff
+				}
+			}
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (t *RetryTransport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodDelete, http.MethodPatch:
+		return true
+	}
+	return false
+}
+
+// retryable reports whether a request that ended with resp or err may
+// succeed when it is sent again.
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns the time to wait before attempt+1: minBackoff doubled
+// for every attempt up to maxBackoff, of which a random half is waited.
+func backoff(attempt int) time.Duration {
+	d := maxBackoff
+	if attempt < 16 {
+		if d = minBackoff << uint(attempt); d > maxBackoff {
+			d = maxBackoff
+		}
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
@@ -0,0 +1,123 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package orion
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+
+	"github.com/YujiAzama/orionclient-go/orionclient"
+)
+
+// GetSubscriptions returns every subscription, following pagination.
+func (c *Client) GetSubscriptions(ctx context.Context, fs string, fsp string) ([]*orionclient.Subscription, error) {
+	var subscriptions []*orionclient.Subscription
+	_, err := Paginate(0, 0, func(page Page) (int, int, error) {
+		items, total, err := c.GetSubscriptionsPage(ctx, page, fs, fsp)
+		subscriptions = append(subscriptions, items...)
+		return len(items), total, err
+	})
+	return subscriptions, err
+}
+
+// GetSubscriptionsPage returns one page of subscriptions and the total
+// number of subscriptions.
+func (c *Client) GetSubscriptionsPage(ctx context.Context, page Page, fs string, fsp string) ([]*orionclient.Subscription, int, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/v2/subscriptions", page.queries(), serviceHeaders(fs, fsp), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var subscriptions []*orionclient.Subscription
+	resp, err := c.doRequest(req, &subscriptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	return subscriptions, totalCount(resp, len(subscriptions)), nil
+}
+
+func (c *Client) GetSubscription(ctx context.Context, id string, fs string, fsp string) (*orionclient.Subscription, error) {
+	relativePath := path.Join("/v2/subscriptions", id)
+	req, err := c.newRequest(ctx, http.MethodGet, relativePath, nil, serviceHeaders(fs, fsp), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var subscription *orionclient.Subscription
+	if _, err := c.doRequest(req, &subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// GetRawSubscriptions returns every subscription as the JSON Orion returns, keeping
+// the fields orionclient.Subscription does not model.
+func (c *Client) GetRawSubscriptions(ctx context.Context, fs string, fsp string) ([]json.RawMessage, error) {
+	return c.getRawList(ctx, "/v2/subscriptions", fs, fsp)
+}
+
+// GetRawSubscriptionsPage returns one page of subscriptions as the JSON Orion returns and
+// the total number of subscriptions.
+func (c *Client) GetRawSubscriptionsPage(ctx context.Context, page Page, fs string, fsp string) ([]json.RawMessage, int, error) {
+	return c.getRawPage(ctx, "/v2/subscriptions", page, fs, fsp)
+}
+
+// GetRawSubscription returns the subscription with the given ID as the JSON Orion
+// returns.
+func (c *Client) GetRawSubscription(ctx context.Context, id string, fs string, fsp string) (json.RawMessage, error) {
+	return c.getRaw(ctx, path.Join("/v2/subscriptions", id), fs, fsp)
+}
+
+// CreateSubscription creates subscription, an orionclient.Subscription or any value
+// encoding a subscription as JSON, and returns its ID.
+func (c *Client) CreateSubscription(ctx context.Context, subscription interface{}, fs string, fsp string) (string, error) {
+	req, err := c.newRequest(ctx, http.MethodPost, "/v2/subscriptions", nil, serviceHeaders(fs, fsp), subscription)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.doRequest(req, nil)
+	if err != nil {
+		return "", err
+	}
+	return locationId(resp), nil
+}
+
+func (c *Client) DeleteSubscription(ctx context.Context, id string, fs string, fsp string) error {
+	relativePath := path.Join("/v2/subscriptions", id)
+	req, err := c.newRequest(ctx, http.MethodDelete, relativePath, nil, serviceHeaders(fs, fsp), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.doRequest(req, nil)
+	return err
+}
+
+// PatchSubscription updates the fields of the subscription given in patch, which is
+// encoded as JSON.
+func (c *Client) PatchSubscription(ctx context.Context, id string, patch interface{}, fs string, fsp string) error {
+	relativePath := path.Join("/v2/subscriptions", id)
+	req, err := c.newRequest(ctx, http.MethodPatch, relativePath, nil, serviceHeaders(fs, fsp), patch)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.doRequest(req, nil)
+	return err
+}
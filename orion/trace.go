@@ -0,0 +1,222 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package orion
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Verbosity levels of TraceTransport.
+const (
+	// TraceRequests logs the method, URL and Fiware headers of every
+	// request, and the status and time of its response.
+	TraceRequests = 1
+	// TraceHeaders also logs all request and response headers.
+	TraceHeaders = 2
+	// TraceBodies also logs the request and response bodies.
+	TraceBodies = 3
+)
+
+// tokenHeaders carry the Orion token and are written as $ORION_TOKEN in
+// curl commands.
+var tokenHeaders = []string{"Authorization", "X-Auth-Token"}
+
+// secretWords mark the names of headers that likely carry a secret.
+var secretWords = []string{"auth", "key", "secret", "token"}
+
+// TraceTransport logs the requests sent to Orion and their responses to
+// Out at Level, and prints an equivalent curl command for every request
+// when Curl is set. Tokens, the headers named in SecretHeaders and headers
+// whose name contains auth, key, secret or token are redacted.
+type TraceTransport struct {
+	Base  http.RoundTripper
+	Out   io.Writer
+	Level int
+	Curl  bool
+	// CurlArgs are added to the curl commands, such as the TLS options
+	// the requests are sent with.
+	CurlArgs []string
+	// SecretHeaders are the names of further headers to redact, such as
+	// the headers given by the user.
+	SecretHeaders []string
+}
+
+func (t *TraceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && (t.Curl || t.Level >= TraceBodies) {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req = req.Clone(req.Context())
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	if t.Curl {
+		fmt.Fprintln(t.Out, t.curl(req, body))
+	}
+	if t.Level >= TraceRequests {
+		fmt.Fprintf(t.Out, "> %s %s\n", req.Method, req.URL)
+		t.printHeader(">", req.Header, body)
+	}
+
+	start := time.Now()
+	resp, err := t.base().RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if t.Level < TraceRequests {
+		return resp, err
+	}
+	if err != nil {
+		fmt.Fprintf(t.Out, "< %v (%s)\n", err, elapsed)
+		return resp, err
+	}
+	fmt.Fprintf(t.Out, "< %s (%s)\n", resp.Status, elapsed)
+	var respBody []byte
+	if t.Level >= TraceBodies {
+		respBody, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+		if err != nil {
+			return resp, err
+		}
+	}
+	t.printHeader("<", resp.Header, respBody)
+	return resp, nil
+}
+
+func (t *TraceTransport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+// printHeader prints the Fiware headers of h, or all of them from
+// TraceHeaders on, followed by body from TraceBodies on.
+func (t *TraceTransport) printHeader(prefix string, h http.Header, body []byte) {
+	for _, name := range sortedHeaderNames(h) {
+		if t.Level < TraceHeaders && !strings.HasPrefix(name, "Fiware-") {
+			continue
+		}
+		for _, value := range h[name] {
+			if t.isSecretHeader(name) {
+				value = replaceToken(value, "REDACTED")
+			}
+			fmt.Fprintf(t.Out, "%s %s: %s\n", prefix, name, value)
+		}
+	}
+	if t.Level >= TraceBodies && len(body) > 0 {
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, body, "", "  ") != nil {
+			pretty.Reset()
+			pretty.Write(body)
+		}
+		fmt.Fprintln(t.Out, strings.TrimRight(pretty.String(), "\n"))
+	}
+}
+
+// curl returns a curl command sending req with body. Tokens are replaced
+// by a reference to the ORION_TOKEN environment variable, and other
+// secrets by a variable named after their header, such as $X_API_KEY.
+func (t *TraceTransport) curl(req *http.Request, body []byte) string {
+	args := []string{"curl", "-X", req.Method, shellQuote(req.URL.String())}
+	for _, arg := range t.CurlArgs {
+		args = append(args, shellQuote(arg))
+	}
+	if req.Host != "" && req.Host != req.URL.Host {
+		args = append(args, "-H", shellQuote("Host: "+req.Host))
+	}
+	for _, name := range sortedHeaderNames(req.Header) {
+		for _, value := range req.Header[name] {
+			if t.isSecretHeader(name) {
+				args = append(args, "-H", "\""+name+": "+replaceToken(value, "$"+secretVariable(name))+"\"")
+				continue
+			}
+			args = append(args, "-H", shellQuote(name+": "+value))
+		}
+	}
+	if len(body) > 0 {
+		args = append(args, "--data-binary", shellQuote(string(body)))
+	}
+	return strings.Join(args, " ")
+}
+
+func sortedHeaderNames(h http.Header) []string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (t *TraceTransport) isSecretHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	for _, secret := range append(tokenHeaders, t.SecretHeaders...) {
+		if http.CanonicalHeaderKey(secret) == name {
+			return true
+		}
+	}
+	lower := strings.ToLower(name)
+	for _, word := range secretWords {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretVariable returns the name of the environment variable standing
+// for the secret sent in the header name in curl commands.
+func secretVariable(name string) string {
+	for _, header := range tokenHeaders {
+		if http.CanonicalHeaderKey(name) == header {
+			return "ORION_TOKEN"
+		}
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// replaceToken replaces the token of a header value with s, keeping an
+// authorization scheme such as Bearer.
+func replaceToken(value, s string) string {
+	if i := strings.Index(value, " "); i > 0 {
+		return value[:i+1] + s
+	}
+	return s
+}
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", "'\\''", -1) + "'"
+}
@@ -0,0 +1,114 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package orion
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+// Entity is an NGSIv2 entity in normalized representation.
+type Entity struct {
+	Id    string
+	Type  string
+	Attrs map[string]Attribute
+}
+
+type Attribute struct {
+	Type     string              `json:"type,omitempty"`
+	Value    interface{}         `json:"value"`
+	Metadata map[string]Metadata `json:"metadata,omitempty"`
+}
+
+type Metadata struct {
+	Type  string      `json:"type,omitempty"`
+	Value interface{} `json:"value"`
+}
+
+// MarshalJSON encodes the entity with id and type first, followed by the
+// attributes in name order.
+func (e Entity) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	write := func(key string, value interface{}) error {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		v, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+		return nil
+	}
+	if e.Id != "" {
+		if err := write("id", e.Id); err != nil {
+			return nil, err
+		}
+	}
+	if e.Type != "" {
+		if err := write("type", e.Type); err != nil {
+			return nil, err
+		}
+	}
+	names := make([]string, 0, len(e.Attrs))
+	for name := range e.Attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := write(name, e.Attrs[name]); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (e *Entity) UnmarshalJSON(data []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*e = Entity{Attrs: map[string]Attribute{}}
+	for name, raw := range m {
+		switch name {
+		case "id":
+			if err := json.Unmarshal(raw, &e.Id); err != nil {
+				return errors.New("entity id must be a string")
+			}
+		case "type":
+			if err := json.Unmarshal(raw, &e.Type); err != nil {
+				return errors.New("entity type must be a string")
+			}
+		default:
+			var attr Attribute
+			if err := json.Unmarshal(raw, &attr); err != nil {
+				return errors.New("attribute \"" + name + "\" must be an object with type and value")
+			}
+			e.Attrs[name] = attr
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+/*
+Copyright © 2020 Yuji Azama <yuji.azama@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package orion
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/YujiAzama/orionclient-go/orionclient"
+)
+
+func (c *Client) GetVersion(ctx context.Context) (*orionclient.Version, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/version", nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var version *orionclient.Version
+	if _, err := c.doRequest(req, &version); err != nil {
+		return nil, err
+	}
+	return version, nil
+}